@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/database"
@@ -39,6 +40,13 @@ func main() {
 		} else {
 			log.Println("Admin user ready (email: " + cfg.AdminEmail + ")")
 		}
+
+		// Expire lapsed view credits and remind investors before theirs lapse
+		if cfg.ExpiryJobInterval > 0 {
+			paymentService := services.NewPaymentService(cfg)
+			emailService := services.NewEmailService(cfg)
+			go paymentService.RunCreditExpiryJob(emailService, time.Duration(cfg.ExpiryJobInterval)*time.Minute)
+		}
 	}()
 
 	// Debug: Log web directory structure
@@ -0,0 +1,333 @@
+// Package authz decides who may do what on the platform. Every permission is
+// declared once in Policies as role, relation, resource kind and action, and
+// handlers and services ask Can instead of comparing roles and IDs by hand.
+package authz
+
+import (
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/models"
+)
+
+type Action string
+
+const (
+	ActionRead     Action = "read"
+	ActionView     Action = "view" // See a project in full rather than its public listing
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionSubmit   Action = "submit"
+	ActionApprove  Action = "approve"
+	ActionRespond  Action = "respond"
+	ActionWithdraw Action = "withdraw"
+	ActionSign     Action = "sign"
+	ActionRefund   Action = "refund"
+	ActionManage   Action = "manage"
+	ActionLeave    Action = "leave" // Leave an organisation, or as its owner remove a member from it
+)
+
+type Kind string
+
+const (
+	KindProject      Kind = "project"
+	KindOffer        Kind = "offer"
+	KindTermSheet    Kind = "term_sheet"
+	KindNDA          Kind = "nda"
+	KindPayment      Kind = "payment"
+	KindKYC          Kind = "kyc"
+	KindOrganization Kind = "organization"
+	KindPlatform     Kind = "platform" // The admin area: users, plans, coupons, categories and reports
+)
+
+// Relation is how a subject stands to a resource
+type Relation string
+
+const (
+	Any          Relation = "any"          // Every resource of the kind
+	Owner        Relation = "owner"        // Projects they created, offers they made, their own NDAs and payments
+	Counterparty Relation = "counterparty" // The developer of the project an offer or term sheet is about
+
+	// Developers the owner of a project added to it, on the project and its
+	// offers and term sheets
+	ProjectEditor Relation = "project_editor"
+	ProjectViewer Relation = "project_viewer"
+
+	// Relations through the subject's organisation. Viewers of an
+	// organisation hold them only for reading.
+	OwnerOrganization        Relation = "owner_organization"        // Resources the subject's organisation owns
+	CounterpartyOrganization Relation = "counterparty_organization" // Offers and term sheets on the subject's organisation's projects
+
+	// Projects the investor, or their organisation, has spent a view credit on
+	Unlocked Relation = "unlocked"
+)
+
+// OfOrganization reports whether the relation is held through an organisation
+func (r Relation) OfOrganization() bool {
+	return r == OwnerOrganization || r == CounterpartyOrganization
+}
+
+// Subject is the user asking for access, with the organisation they belong
+// to if any
+type Subject struct {
+	ID               uuid.UUID
+	Role             models.UserRole
+	OrganizationID   uuid.UUID
+	OrganizationRole models.OrganizationRole
+}
+
+// PartyID returns the ID a resource must carry for the subject to stand in
+// the relation to it: the user's own ID, or their organisation's
+func (sub Subject) PartyID(rel Relation) uuid.UUID {
+	if rel.OfOrganization() {
+		return sub.OrganizationID
+	}
+	return sub.ID
+}
+
+// Resource is the thing being accessed, reduced to the users and
+// organisations it relates to
+type Resource struct {
+	Kind                       Kind
+	OwnerID                    uuid.UUID
+	CounterpartyID             uuid.UUID
+	OwnerOrganizationID        uuid.UUID
+	CounterpartyOrganizationID uuid.UUID
+	Collaborators              map[uuid.UUID]models.ProjectPermission // On the project, or the project an offer or term sheet is about
+	Unlocked                   bool                                   // The subject holds a view of the project; set by the caller
+}
+
+// Policy permits users of a role to perform an action on resources of a kind
+// they stand in the given relation to
+type Policy struct {
+	Role     models.UserRole
+	Relation Relation
+	Kind     Kind
+	Action   Action
+}
+
+// Policies lists every permission on the platform. Anything not listed is denied.
+var Policies = concat(
+	// Developers manage their own projects; admins manage and approve all of them
+	grant(models.RoleDeveloper, Owner, KindProject, ActionRead, ActionView, ActionCreate, ActionUpdate, ActionSubmit, ActionManage),
+	grant(models.RoleDeveloper, ProjectEditor, KindProject, ActionRead, ActionView, ActionUpdate, ActionSubmit),
+	grant(models.RoleDeveloper, ProjectViewer, KindProject, ActionRead, ActionView),
+	grant(models.RoleDeveloper, OwnerOrganization, KindProject, ActionRead, ActionView, ActionUpdate, ActionSubmit),
+	grant(models.RoleAdmin, Any, KindProject, ActionRead, ActionView, ActionCreate, ActionUpdate, ActionSubmit, ActionApprove),
+
+	// Investors see a project in full once they pay to view it
+	grant(models.RoleInvestor, Unlocked, KindProject, ActionView),
+
+	// Investors make and withdraw offers; the project's developer responds
+	grant(models.RoleInvestor, Owner, KindOffer, ActionRead, ActionCreate, ActionWithdraw),
+	grant(models.RoleInvestor, OwnerOrganization, KindOffer, ActionRead, ActionWithdraw),
+	grant(models.RoleDeveloper, Counterparty, KindOffer, ActionRead, ActionRespond),
+	grant(models.RoleDeveloper, CounterpartyOrganization, KindOffer, ActionRead, ActionRespond),
+	grant(models.RoleDeveloper, ProjectEditor, KindOffer, ActionRead, ActionRespond),
+	grant(models.RoleDeveloper, ProjectViewer, KindOffer, ActionRead),
+	grant(models.RoleAdmin, Any, KindOffer, ActionRead),
+
+	// Both parties to an accepted offer sign its term sheet
+	grant(models.RoleInvestor, Owner, KindTermSheet, ActionRead, ActionSign),
+	grant(models.RoleInvestor, OwnerOrganization, KindTermSheet, ActionRead, ActionSign),
+	grant(models.RoleDeveloper, Counterparty, KindTermSheet, ActionRead, ActionSign),
+	grant(models.RoleDeveloper, CounterpartyOrganization, KindTermSheet, ActionRead, ActionSign),
+	grant(models.RoleDeveloper, ProjectEditor, KindTermSheet, ActionRead, ActionSign),
+	grant(models.RoleDeveloper, ProjectViewer, KindTermSheet, ActionRead),
+	grant(models.RoleAdmin, Any, KindTermSheet, ActionRead),
+
+	grant(models.RoleInvestor, Owner, KindNDA, ActionRead, ActionCreate),
+	grant(models.RoleInvestor, OwnerOrganization, KindNDA, ActionRead),
+
+	// Credits an organisation bought are shared, but only the buyer refunds them
+	grant(models.RoleInvestor, Owner, KindPayment, ActionRead, ActionCreate, ActionRefund),
+	grant(models.RoleInvestor, OwnerOrganization, KindPayment, ActionRead),
+	grant(models.RoleAdmin, Any, KindPayment, ActionRead, ActionRefund),
+
+	// Investors submit their own KYC documents; admins review them
+	grant(models.RoleInvestor, Owner, KindKYC, ActionRead, ActionCreate),
+	grant(models.RoleAdmin, Any, KindKYC, ActionRead, ActionApprove),
+
+	// Investors form investment firms and developers startups. Which members
+	// may manage an organisation is decided by their role in it.
+	grant(models.RoleInvestor, Owner, KindOrganization, ActionRead, ActionCreate, ActionManage, ActionLeave),
+	grant(models.RoleDeveloper, Owner, KindOrganization, ActionRead, ActionCreate, ActionManage, ActionLeave),
+	grant(models.RoleAdmin, Any, KindOrganization, ActionRead),
+
+	grant(models.RoleAdmin, Any, KindPlatform, ActionManage),
+)
+
+func grant(role models.UserRole, relation Relation, kind Kind, actions ...Action) []Policy {
+	policies := make([]Policy, len(actions))
+	for i, action := range actions {
+		policies[i] = Policy{Role: role, Relation: relation, Kind: kind, Action: action}
+	}
+	return policies
+}
+
+func concat(groups ...[]Policy) []Policy {
+	var all []Policy
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+	return all
+}
+
+// Can reports whether the subject may perform the action on the resource
+func Can(sub Subject, action Action, res Resource) bool {
+	for _, rel := range Scope(sub, action, res.Kind) {
+		if rel == Any || Holds(sub, rel, res) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether a role may perform the action on at least some
+// resources of the kind. Routes check it before the resource is loaded.
+func Allowed(role models.UserRole, action Action, kind Kind) bool {
+	return len(Scope(Subject{Role: role}, action, kind)) > 0
+}
+
+// CanAll reports whether the subject may perform the action on every resource
+// of the kind, not just ones they are a party to
+func CanAll(sub Subject, action Action, kind Kind) bool {
+	for _, rel := range Scope(sub, action, kind) {
+		if rel == Any {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns the relations under which the subject may perform the action
+// on resources of the kind, so list queries can be filtered to match
+func Scope(sub Subject, action Action, kind Kind) []Relation {
+	readOnly := sub.OrganizationRole == models.OrganizationRoleViewer && action != ActionRead && action != ActionView
+
+	var relations []Relation
+	for _, p := range Policies {
+		if p.Role == sub.Role && p.Action == action && p.Kind == kind {
+			if readOnly && p.Relation.OfOrganization() {
+				continue
+			}
+			relations = append(relations, p.Relation)
+		}
+	}
+	return relations
+}
+
+// Holds reports whether the subject stands in the relation to the resource
+func Holds(sub Subject, rel Relation, res Resource) bool {
+	id := sub.PartyID(rel)
+	if id == uuid.Nil {
+		return false
+	}
+
+	switch rel {
+	case Owner:
+		return id == res.OwnerID
+	case Counterparty:
+		return id == res.CounterpartyID
+	case OwnerOrganization:
+		return id == res.OwnerOrganizationID
+	case CounterpartyOrganization:
+		return id == res.CounterpartyOrganizationID
+	case ProjectEditor:
+		return res.Collaborators[id] == models.ProjectPermissionEditor
+	case ProjectViewer:
+		return res.Collaborators[id] == models.ProjectPermissionViewer
+	case Unlocked:
+		return res.Unlocked
+	default:
+		return false
+	}
+}
+
+// RelationOf returns how the subject stands to the resource, or "" if the
+// subject is not one of its parties. Their own part is preferred over their
+// organisation's.
+func RelationOf(sub Subject, res Resource) Relation {
+	for _, rel := range []Relation{Owner, Counterparty, ProjectEditor, ProjectViewer, OwnerOrganization, CounterpartyOrganization} {
+		if Holds(sub, rel, res) {
+			return rel
+		}
+	}
+	return ""
+}
+
+// Own describes a new resource of the kind that the subject would own, for
+// checking create actions
+func Own(sub Subject, kind Kind) Resource {
+	return Resource{Kind: kind, OwnerID: sub.ID}
+}
+
+// Project describes a project, owned by its developer and their startup. The
+// project's Collaborators must be loaded for them to be recognised.
+func Project(p *models.Project) Resource {
+	return Resource{
+		Kind:                KindProject,
+		OwnerID:             p.DeveloperID,
+		OwnerOrganizationID: orNil(p.OrganizationID),
+		Collaborators:       collaborators(p),
+	}
+}
+
+// Offer describes an offer, owned by the investor who made it and the firm
+// it was made for. The offer's Project must be loaded for the developer and
+// their startup to be recognised, and Project.Collaborators for its
+// collaborators.
+func Offer(o *models.InvestmentOffer) Resource {
+	res := Resource{Kind: KindOffer, OwnerID: o.InvestorID, OwnerOrganizationID: orNil(o.OrganizationID)}
+	if o.Project != nil {
+		res.CounterpartyID = o.Project.DeveloperID
+		res.CounterpartyOrganizationID = orNil(o.Project.OrganizationID)
+		res.Collaborators = collaborators(o.Project)
+	}
+	return res
+}
+
+// TermSheet describes a term sheet through the offer it settles. The term
+// sheet's Offer and Offer.Project, and Offer.Project.Collaborators for its
+// collaborators, must be loaded.
+func TermSheet(t *models.TermSheet) Resource {
+	if t.Offer == nil {
+		return Resource{Kind: KindTermSheet}
+	}
+	res := Offer(t.Offer)
+	res.Kind = KindTermSheet
+	return res
+}
+
+// Payment describes a payment, owned by the investor who made it and the
+// firm it was made for
+func Payment(p *models.Payment) Resource {
+	return Resource{Kind: KindPayment, OwnerID: p.InvestorID, OwnerOrganizationID: orNil(p.OrganizationID)}
+}
+
+// NDA describes an NDA, owned by the investor who signed it and the firm it
+// was signed for
+func NDA(n *models.NDA) Resource {
+	return Resource{Kind: KindNDA, OwnerID: n.InvestorID, OwnerOrganizationID: orNil(n.OrganizationID)}
+}
+
+// KYCSubmission describes a KYC submission, owned by the investor who sent it
+func KYCSubmission(k *models.KYCSubmission) Resource {
+	return Resource{Kind: KindKYC, OwnerID: k.InvestorID}
+}
+
+func collaborators(p *models.Project) map[uuid.UUID]models.ProjectPermission {
+	if len(p.Collaborators) == 0 {
+		return nil
+	}
+	permissions := make(map[uuid.UUID]models.ProjectPermission, len(p.Collaborators))
+	for _, c := range p.Collaborators {
+		permissions[c.UserID] = c.Permission
+	}
+	return permissions
+}
+
+func orNil(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -15,21 +16,58 @@ type Config struct {
 	DatabaseType string // "postgres" or "sqlite"
 
 	// JWT
-	JWTSecret     string
-	JWTExpiration int // hours
+	JWTSecret        string
+	AccessTokenTTL   int // minutes
+	RefreshTokenTTL  int // days; a session not refreshed for this long ends
+	ImpersonationTTL int // minutes an admin's impersonation token lasts; it cannot be refreshed
+
+	// Two-factor authentication
+	RequireAdmin2FA   bool // admins must sign in with a second factor to use the admin area
+	RequireSigning2FA bool // term sheets can only be signed from a session that passed 2FA
+
+	// Login throttling
+	LoginLimiter        string // "database" to share attempt counts between instances, or "memory"
+	LoginMaxFailures    int    // failed logins before an account is locked
+	LoginMaxIPFailures  int    // failed logins from one IP address before it is blocked
+	LoginLockoutMinutes int
+
+	// OIDCProviders are the OpenID Connect identity providers users may sign
+	// in with, keyed by the name used in their login URLs
+	OIDCProviders map[string]OIDCProvider
 
 	// Stripe
 	StripeSecretKey      string
 	StripePublishableKey string
 	StripeWebhookSecret  string
 
+	// Paystack
+	PaystackSecretKey string
+	PaystackBaseURL   string
+
 	// Payment
 	ViewFeeAmount   int64  // in cents
 	ViewFeeCurrency string // e.g., "usd", "zar"
 	MaxProjectViews int    // max projects per payment
+	DemoMode        bool   // use the fake payment provider when Stripe is not configured
+	SelfRefunds     bool   // allow investors to refund their own unused credits
+
+	// Credit expiry
+	CreditValidityDays int // days credits last when the plan sets no validity, 0 for no expiry
+	ExpiryReminderDays int // days before expiry to remind investors of unused credits, 0 to disable
+	ExpiryJobInterval  int // minutes between runs of the credit expiry job
+
+	// CurrencyProviders picks a payment provider per currency, e.g. "zar" -> "paystack".
+	// Currencies not listed use Stripe, or the fake provider in demo mode.
+	CurrencyProviders map[string]string
+
+	// Invoicing
+	VATRate        float64 // percentage included in view fees, 0 to leave VAT off invoices
+	VATNumber      string  // the platform's VAT registration number
+	InvoiceAddress string  // the platform's postal address printed on invoices
 
 	// Storage
-	UploadDir string
+	UploadDir    string
+	KYCUploadDir string // KYC documents; kept apart from UploadDir, which is served publicly
 
 	// Email
 	SMTPHost     string
@@ -55,21 +93,54 @@ func Load() *Config {
 		DatabaseType: getEnv("DATABASE_TYPE", "sqlite"),
 
 		// JWT
-		JWTSecret:     getEnv("JWT_SECRET", "your-super-secret-key-change-in-production"),
-		JWTExpiration: getEnvInt("JWT_EXPIRATION", 72),
+		JWTSecret:        getEnv("JWT_SECRET", "your-super-secret-key-change-in-production"),
+		AccessTokenTTL:   getEnvInt("ACCESS_TOKEN_TTL", 15),
+		RefreshTokenTTL:  getEnvInt("REFRESH_TOKEN_TTL", 30),
+		ImpersonationTTL: getEnvInt("IMPERSONATION_TTL", 15),
+
+		// Two-factor authentication
+		RequireAdmin2FA:   getEnvBool("REQUIRE_ADMIN_2FA", false),
+		RequireSigning2FA: getEnvBool("REQUIRE_SIGNING_2FA", false),
+
+		// Login throttling
+		LoginLimiter:        getEnv("LOGIN_LIMITER", "database"),
+		LoginMaxFailures:    getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginMaxIPFailures:  getEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+		LoginLockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+
+		OIDCProviders: loadOIDCProviders(),
 
 		// Stripe
 		StripeSecretKey:      getEnv("STRIPE_SECRET_KEY", ""),
 		StripePublishableKey: getEnv("STRIPE_PUBLISHABLE_KEY", ""),
 		StripeWebhookSecret:  getEnv("STRIPE_WEBHOOK_SECRET", ""),
 
+		// Paystack
+		PaystackSecretKey: getEnv("PAYSTACK_SECRET_KEY", ""),
+		PaystackBaseURL:   getEnv("PAYSTACK_BASE_URL", "https://api.paystack.co"),
+
 		// Payment
 		ViewFeeAmount:   getEnvInt64("VIEW_FEE_AMOUNT", 50000), // $500 in cents
 		ViewFeeCurrency: getEnv("VIEW_FEE_CURRENCY", "usd"),
 		MaxProjectViews: getEnvInt("MAX_PROJECT_VIEWS", 4),
+		DemoMode:        getEnvBool("DEMO_MODE", false),
+		SelfRefunds:     getEnvBool("SELF_SERVICE_REFUNDS", false),
+
+		// Credit expiry
+		CreditValidityDays: getEnvInt("CREDIT_VALIDITY_DAYS", 0),
+		ExpiryReminderDays: getEnvInt("CREDIT_EXPIRY_REMINDER_DAYS", 7),
+		ExpiryJobInterval:  getEnvInt("CREDIT_EXPIRY_JOB_INTERVAL", 60),
+
+		CurrencyProviders: getEnvMap("PAYMENT_PROVIDERS", map[string]string{"zar": "paystack"}),
+
+		// Invoicing
+		VATRate:        getEnvFloat("VAT_RATE", 15),
+		VATNumber:      getEnv("VAT_NUMBER", ""),
+		InvoiceAddress: getEnv("INVOICE_ADDRESS", ""),
 
 		// Storage
-		UploadDir: getEnv("UPLOAD_DIR", "./uploads"),
+		UploadDir:    getEnv("UPLOAD_DIR", "./uploads"),
+		KYCUploadDir: getEnv("KYC_UPLOAD_DIR", "./kyc_uploads"),
 
 		// Email
 		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
@@ -85,6 +156,43 @@ func Load() *Config {
 	}
 }
 
+// OIDCProvider is an OpenID Connect identity provider, e.g. Google or LinkedIn
+type OIDCProvider struct {
+	Name         string
+	Issuer       string // Discovery is read from Issuer + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS, e.g.
+// "google,linkedin", each configured by OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET and optionally _SCOPES. Providers without an issuer or
+// client ID are skipped.
+func loadOIDCProviders() map[string]OIDCProvider {
+	providers := make(map[string]OIDCProvider)
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(getEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			continue
+		}
+		providers[name] = provider
+	}
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -101,6 +209,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvInt64(key string, defaultValue int64) int64 {
 	if value := os.Getenv(key); value != "" {
 		if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
@@ -110,6 +227,34 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvMap parses comma-separated key:value pairs, e.g. "zar:paystack,usd:stripe".
+// Keys and values are lowercased.
+func getEnvMap(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		result[strings.ToLower(strings.TrimSpace(k))] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return result
+}
+
 // getEnvWithFallback checks primary key first, then fallback, then default
 func getEnvWithFallback(primary, fallback, defaultValue string) string {
 	if value := os.Getenv(primary); value != "" {
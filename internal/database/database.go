@@ -2,8 +2,10 @@ package database
 
 import (
 	"log"
+	"time"
 
 	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/models"
 	"gorm.io/driver/postgres"
@@ -39,7 +41,7 @@ func Initialize(cfg *config.Config) error {
 	}
 
 	// Seed initial data
-	if err := seedData(); err != nil {
+	if err := seedData(cfg); err != nil {
 		log.Printf("Warning: seed data error: %v", err)
 	}
 
@@ -47,7 +49,11 @@ func Initialize(cfg *config.Config) error {
 }
 
 func autoMigrate() error {
-	return DB.AutoMigrate(
+	if err := dedupeProjectViews(); err != nil {
+		return err
+	}
+
+	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Category{},
 		&models.Project{},
@@ -58,12 +64,254 @@ func autoMigrate() error {
 		&models.ProjectView{},
 		&models.InvestmentOffer{},
 		&models.TermSheet{},
-	)
+		&models.WebhookEvent{},
+		&models.CreditLedgerEntry{},
+		&models.PricingPlan{},
+		&models.Invoice{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Session{},
+		&models.RecoveryCode{},
+		&models.AuditLog{},
+		&models.LoginAttempt{},
+		&models.UserIdentity{},
+		&models.OIDCLogin{},
+		&models.UserToken{},
+		&models.KYCSubmission{},
+		&models.KYCDocument{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.OrganizationInvitation{},
+		&models.ProjectCollaborator{},
+		&models.ProjectInvitation{},
+		&models.FakeIntent{},
+		&models.FakeRefund{},
+	); err != nil {
+		return err
+	}
+
+	if err := migrateCreditLedger(); err != nil {
+		return err
+	}
+	if err := migrateUserTokens(); err != nil {
+		return err
+	}
+	return migrateProjectOwners()
 }
 
-func seedData() error {
+// dedupeProjectViews removes duplicate investor/project views so the unique
+// index on project_views can be created on databases that predate it
+func dedupeProjectViews() error {
+	if !DB.Migrator().HasTable(&models.ProjectView{}) {
+		return nil
+	}
+
+	var duplicates []struct {
+		InvestorID uuid.UUID
+		ProjectID  uuid.UUID
+	}
+	if err := DB.Model(&models.ProjectView{}).
+		Select("investor_id, project_id").
+		Group("investor_id, project_id").
+		Having("COUNT(*) > 1").
+		Scan(&duplicates).Error; err != nil {
+		return err
+	}
+
+	for _, d := range duplicates {
+		var views []models.ProjectView
+		if err := DB.Where("investor_id = ? AND project_id = ?", d.InvestorID, d.ProjectID).
+			Order("viewed_at ASC").
+			Find(&views).Error; err != nil {
+			return err
+		}
+
+		// Keep the earliest view
+		for _, v := range views[1:] {
+			if err := DB.Delete(&models.ProjectView{}, "id = ?", v.ID).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("Removed %d duplicate views for investor %s on project %s", len(views)-1, d.InvestorID, d.ProjectID)
+	}
+
+	return nil
+}
+
+// migrateCreditLedger backfills ledger entries from the projects_remaining
+// counter that payments carried before the credit ledger, then drops the column
+func migrateCreditLedger() error {
+	if !DB.Migrator().HasColumn(&models.Payment{}, "projects_remaining") {
+		return nil
+	}
+
+	var payments []struct {
+		ID                uuid.UUID
+		InvestorID        uuid.UUID
+		Status            models.PaymentStatus
+		ProjectsTotal     int
+		ProjectsRemaining int
+	}
+	if err := DB.Table("payments").
+		Select("id, investor_id, status, projects_total, projects_remaining").
+		Where("status IN ?", []models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusRefunded}).
+		Scan(&payments).Error; err != nil {
+		return err
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range payments {
+			var existing int64
+			if err := tx.Model(&models.CreditLedgerEntry{}).Where("payment_id = ?", p.ID).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+
+			entries := []models.CreditLedgerEntry{
+				{InvestorID: p.InvestorID, PaymentID: p.ID, Type: models.CreditEntryPurchase, Delta: p.ProjectsTotal, Reason: "Migrated from projects_remaining"},
+			}
+			if used := p.ProjectsTotal - p.ProjectsRemaining; used > 0 {
+				entries = append(entries, models.CreditLedgerEntry{InvestorID: p.InvestorID, PaymentID: p.ID, Type: models.CreditEntryConsume, Delta: -used, Reason: "Migrated from projects_remaining"})
+			}
+			if p.Status == models.PaymentStatusRefunded && p.ProjectsRemaining > 0 {
+				entries = append(entries, models.CreditLedgerEntry{InvestorID: p.InvestorID, PaymentID: p.ID, Type: models.CreditEntryRefund, Delta: -p.ProjectsRemaining, Reason: "Migrated from projects_remaining"})
+			}
+
+			if err := tx.Create(&entries).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Migrated %d payments to the credit ledger", len(payments))
+	return DB.Migrator().DropColumn(&models.Payment{}, "projects_remaining")
+}
+
+// migrateUserTokens moves the verification and reset tokens that users
+// carried in plaintext before the user_tokens table into it, hashed, then
+// drops the old columns. Verification tokens from before they expired get
+// the current lifetime from now. Unused magic links from the table they had
+// before are dropped; they lasted minutes and users can request new ones.
+func migrateUserTokens() error {
+	if DB.Migrator().HasTable("magic_links") {
+		if err := DB.Migrator().DropTable("magic_links"); err != nil {
+			return err
+		}
+	}
+
+	columns := []string{"verify_token", "verify_expires", "reset_token", "reset_expires"}
+	if !DB.Migrator().HasColumn(&models.User{}, "verify_token") {
+		return nil
+	}
+
+	var users []struct {
+		ID            uuid.UUID
+		VerifyToken   string
+		VerifyExpires *time.Time
+		ResetToken    string
+		ResetExpires  *time.Time
+	}
+	if err := DB.Table("users").
+		Select("id, verify_token, verify_expires, reset_token, reset_expires").
+		Where("(verify_token IS NOT NULL AND verify_token <> '') OR (reset_token IS NOT NULL AND reset_token <> '')").
+		Scan(&users).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var tokens []models.UserToken
+	for _, u := range users {
+		if u.VerifyToken != "" {
+			expires := now.Add(48 * time.Hour)
+			if u.VerifyExpires != nil {
+				expires = *u.VerifyExpires
+			}
+			tokens = append(tokens, models.UserToken{UserID: u.ID, Purpose: models.TokenPurposeVerifyEmail, TokenHash: models.HashToken(u.VerifyToken), ExpiresAt: expires})
+		}
+		if u.ResetToken != "" && u.ResetExpires != nil && u.ResetExpires.After(now) {
+			tokens = append(tokens, models.UserToken{UserID: u.ID, Purpose: models.TokenPurposePasswordReset, TokenHash: models.HashToken(u.ResetToken), ExpiresAt: *u.ResetExpires})
+		}
+	}
+
+	if len(tokens) > 0 {
+		if err := DB.Create(&tokens).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("Migrated %d plaintext user tokens", len(tokens))
+
+	for _, column := range columns {
+		if !DB.Migrator().HasColumn(&models.User{}, column) {
+			continue
+		}
+		if err := DB.Migrator().DropColumn(&models.User{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateProjectOwners gives projects created before collaborators their
+// developer as owner collaborator
+func migrateProjectOwners() error {
+	var projects []models.Project
+	if err := DB.Unscoped().Select("id, developer_id").
+		Where("id NOT IN (?)", DB.Model(&models.ProjectCollaborator{}).Select("project_id").Where("permission = ?", models.ProjectPermissionOwner)).
+		Find(&projects).Error; err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return nil
+	}
+
+	owners := make([]models.ProjectCollaborator, len(projects))
+	for i, p := range projects {
+		owners[i] = models.ProjectCollaborator{ProjectID: p.ID, UserID: p.DeveloperID, Permission: models.ProjectPermissionOwner}
+	}
+	if err := DB.Create(&owners).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Added owners to %d projects", len(projects))
+	return nil
+}
+
+func seedData(cfg *config.Config) error {
 	// Seed categories if empty
-	return SeedCategories()
+	if err := SeedCategories(); err != nil {
+		return err
+	}
+	return SeedPricingPlans(cfg)
+}
+
+// SeedPricingPlans creates the default plan from the configured view fee if no plans exist
+func SeedPricingPlans(cfg *config.Config) error {
+	var count int64
+	if err := DB.Unscoped().Model(&models.PricingPlan{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	plan := &models.PricingPlan{
+		Name:     "Standard",
+		Amount:   cfg.ViewFeeAmount,
+		Currency: cfg.ViewFeeCurrency,
+		Credits:  cfg.MaxProjectViews,
+		Active:   true,
+	}
+	if err := DB.Create(plan).Error; err != nil {
+		return err
+	}
+	log.Println("Seeded default pricing plan")
+	return nil
 }
 
 // SeedCategories populates the database with default categories
@@ -184,6 +184,9 @@ func SeedProjects() error {
 			EquityOffered: float64(5 + rand.Intn(15)),
 			Status:        models.ProjectStatusApproved,
 			PitchContent:  generatePitch(p.Title, p.Problem, p.Solution),
+			Collaborators: []models.ProjectCollaborator{
+				{UserID: developer.ID, Permission: models.ProjectPermissionOwner},
+			},
 		}
 
 		DB.Create(project)
@@ -9,19 +9,28 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/middleware"
 	"github.com/ukuvago/angel-platform/internal/models"
 	"github.com/ukuvago/angel-platform/internal/services"
 )
 
 type AdminHandler struct {
-	emailService *services.EmailService
-	authService  *services.AuthService
+	emailService    *services.EmailService
+	authService     *services.AuthService
+	paymentService  *services.PaymentService
+	documentService *services.DocumentService
+	loginLimiter    *services.LoginLimiter
+	accountService  *services.AccountService
 }
 
-func NewAdminHandler(emailService *services.EmailService, authService *services.AuthService) *AdminHandler {
+func NewAdminHandler(emailService *services.EmailService, authService *services.AuthService, paymentService *services.PaymentService, documentService *services.DocumentService, loginLimiter *services.LoginLimiter, accountService *services.AccountService) *AdminHandler {
 	return &AdminHandler{
-		emailService: emailService,
-		authService:  authService,
+		emailService:    emailService,
+		authService:     authService,
+		paymentService:  paymentService,
+		documentService: documentService,
+		loginLimiter:    loginLimiter,
+		accountService:  accountService,
 	}
 }
 
@@ -41,16 +50,19 @@ func (h *AdminHandler) GetDashboardStats(c *gin.Context) {
 	}
 
 	var stats struct {
-		TotalUsers       int64 `json:"total_users"`
-		TotalInvestors   int64 `json:"total_investors"`
-		TotalDevelopers  int64 `json:"total_developers"`
-		TotalProjects    int64 `json:"total_projects"`
-		ApprovedProjects int64 `json:"approved_projects"`
-		PendingProjects  int64 `json:"pending_projects"`
-		TotalOffers      int64 `json:"total_offers"`
-		AcceptedOffers   int64 `json:"accepted_offers"`
-		TotalPayments    int64 `json:"total_payments"`
-		TotalRevenue     int64 `json:"total_revenue"`
+		TotalUsers        int64 `json:"total_users"`
+		TotalInvestors    int64 `json:"total_investors"`
+		TotalDevelopers   int64 `json:"total_developers"`
+		TotalProjects     int64 `json:"total_projects"`
+		ApprovedProjects  int64 `json:"approved_projects"`
+		PendingProjects   int64 `json:"pending_projects"`
+		TotalOffers       int64 `json:"total_offers"`
+		AcceptedOffers    int64 `json:"accepted_offers"`
+		TotalPayments     int64 `json:"total_payments"`
+		TotalRevenue      int64 `json:"total_revenue"`
+		TotalRefunds      int64 `json:"total_refunds"`
+		CouponRedemptions int64 `json:"coupon_redemptions"`
+		TotalDiscounts    int64 `json:"total_discounts"`
 	}
 
 	// Helper to log errors but continue if possible (or fail fast if critical)
@@ -76,11 +88,24 @@ func (h *AdminHandler) GetDashboardStats(c *gin.Context) {
 
 	check(db.Model(&models.Payment{}).Where("status = ?", models.PaymentStatusCompleted).Count(&stats.TotalPayments).Error, "Count Payments")
 
-	// Revenue Calculation
+	// Pending refunds are already taken off revenue, so they count here too
+	var refunds sql.NullInt64
+	check(db.Model(&models.Payment{}).
+		Where("status IN ?", []models.PaymentStatus{models.PaymentStatusRefundPending, models.PaymentStatusRefunded}).
+		Select("SUM(refunded_amount)").
+		Scan(&refunds).Error, "Sum Refunds")
+	stats.TotalRefunds = refunds.Int64
+
+	var discounts sql.NullInt64
+	check(db.Model(&models.CouponRedemption{}).Count(&stats.CouponRedemptions).Error, "Count Coupon Redemptions")
+	check(db.Model(&models.CouponRedemption{}).Select("SUM(discount)").Scan(&discounts).Error, "Sum Discounts")
+	stats.TotalDiscounts = discounts.Int64
+
+	// Revenue Calculation, net of refunds
 	var revenue sql.NullInt64
 	err := db.Model(&models.Payment{}).
-		Where("status = ?", models.PaymentStatusCompleted).
-		Select("SUM(amount)").
+		Where("status IN ?", []models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusRefundPending, models.PaymentStatusRefunded}).
+		Select("SUM(amount - refunded_amount)").
 		Scan(&revenue).Error
 
 	if err != nil {
@@ -259,7 +284,119 @@ func (h *AdminHandler) ListAllPayments(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"payments": payments})
+	if err := h.paymentService.LoadCreditBalances(payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch credit balances"})
+		return
+	}
+
+	var gross, refunded int64
+	for _, p := range payments {
+		if p.Status == models.PaymentStatusCompleted || p.Status == models.PaymentStatusRefundPending || p.Status == models.PaymentStatusRefunded {
+			gross += p.Amount
+			refunded += p.RefundedAmount
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payments":      payments,
+		"gross_revenue": gross,
+		"refunded":      refunded,
+		"net_revenue":   gross - refunded,
+	})
+}
+
+// GetPaymentLedger returns the credit ledger of a payment
+func (h *AdminHandler) GetPaymentLedger(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	entries, err := h.paymentService.GetPaymentLedger(paymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// RefundPaymentRequest represents an admin refund
+type RefundPaymentRequest struct {
+	Mode   services.RefundMode `json:"mode" binding:"required,oneof=full pro_rata"`
+	Reason string              `json:"reason" binding:"required"`
+}
+
+// RefundPayment refunds a payment in full or for its unused credits
+func (h *AdminHandler) RefundPayment(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := h.paymentService.RefundPayment(adminID, paymentID, req.Mode, req.Reason)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment refunded successfully",
+		"payment": payment.ToResponse(),
+	})
+}
+
+// GrantCreditsRequest represents an admin credit grant
+type GrantCreditsRequest struct {
+	Credits      int    `json:"credits" binding:"required,gt=0"`
+	ValidityDays int    `json:"validity_days" binding:"gte=0"` // Defaults to CREDIT_VALIDITY_DAYS
+	Reason       string `json:"reason" binding:"required"`
+}
+
+// GrantCredits gives an investor complimentary project views
+func (h *AdminHandler) GrantCredits(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	investorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req GrantCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := h.paymentService.GrantCredits(adminID, investorID, req.Credits, req.ValidityDays, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Credits granted successfully",
+		"payment": payment.ToResponse(),
+	})
 }
 
 // CreateCategory creates a new category
@@ -356,3 +493,31 @@ func (h *AdminHandler) DeleteCategory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
+
+// UnlockUser lifts a lockout caused by repeated failed sign-ins
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.loginLimiter.Unlock(adminID, user, c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
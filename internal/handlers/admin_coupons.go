@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+)
+
+// CouponRequest represents coupon create/update input
+type CouponRequest struct {
+	Code           string            `json:"code" binding:"required"`
+	Description    string            `json:"description"`
+	Type           models.CouponType `json:"type" binding:"required,oneof=percent fixed"`
+	Value          int64             `json:"value" binding:"gt=0"` // Percentage, or cents for fixed coupons
+	Currency       string            `json:"currency"`
+	MaxRedemptions int               `json:"max_redemptions" binding:"gte=0"`
+	ExpiresAt      *time.Time        `json:"expires_at"`
+	PlanID         *uuid.UUID        `json:"plan_id"`
+	UserID         *uuid.UUID        `json:"user_id"`
+	Active         bool              `json:"active"`
+}
+
+func (req *CouponRequest) apply(coupon *models.Coupon) {
+	coupon.Code = models.NormalizeCouponCode(req.Code)
+	coupon.Description = req.Description
+	coupon.Type = req.Type
+	coupon.Value = req.Value
+	coupon.Currency = req.Currency
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.ExpiresAt = req.ExpiresAt
+	coupon.PlanID = req.PlanID
+	coupon.UserID = req.UserID
+	coupon.Active = req.Active
+}
+
+func (req *CouponRequest) validate() string {
+	if models.NormalizeCouponCode(req.Code) == "" {
+		return "Coupon code is required"
+	}
+	if req.Type == models.CouponTypePercent && req.Value > 100 {
+		return "Percentage coupons cannot exceed 100"
+	}
+	if req.Type == models.CouponTypeFixed && len(req.Currency) != 3 {
+		return "Fixed coupons require a currency"
+	}
+	return ""
+}
+
+// codeTaken reports whether another coupon, including a deleted one, already uses the code
+func (req *CouponRequest) codeTaken(exceptID uuid.UUID) bool {
+	var count int64
+	database.GetDB().Unscoped().Model(&models.Coupon{}).
+		Where("code = ? AND id <> ?", models.NormalizeCouponCode(req.Code), exceptID).
+		Count(&count)
+	return count > 0
+}
+
+// ListCoupons returns all coupons with their redemption counts
+func (h *AdminHandler) ListCoupons(c *gin.Context) {
+	db := database.GetDB()
+
+	var coupons []models.Coupon
+	if err := db.Order("created_at DESC").Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// CreateCoupon creates a new coupon
+func (h *AdminHandler) CreateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if req.codeTaken(uuid.Nil) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	coupon := &models.Coupon{}
+	req.apply(coupon)
+
+	db := database.GetDB()
+	if err := db.Create(coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+// UpdateCoupon updates a coupon. Payments that already used it keep their discount.
+func (h *AdminHandler) UpdateCoupon(c *gin.Context) {
+	couponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	db := database.GetDB()
+
+	var coupon models.Coupon
+	if err := db.First(&coupon, "id = ?", couponID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	if req.codeTaken(coupon.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	req.apply(&coupon)
+
+	if err := db.Save(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
+
+// DeleteCoupon retires a coupon. Its redemptions are kept for reporting.
+func (h *AdminHandler) DeleteCoupon(c *gin.Context) {
+	couponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	db := database.GetDB()
+	if err := db.Delete(&models.Coupon{}, "id = ?", couponID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
+
+// GetCouponRedemptions reports who redeemed a coupon and the discount given
+func (h *AdminHandler) GetCouponRedemptions(c *gin.Context) {
+	couponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	redemptions, err := h.paymentService.GetCouponRedemptions(couponID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redemptions"})
+		return
+	}
+
+	var totalDiscount int64
+	for _, r := range redemptions {
+		totalDiscount += r.Discount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redemptions":    redemptions,
+		"total_discount": totalDiscount,
+	})
+}
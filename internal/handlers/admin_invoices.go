@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportInvoices exports invoices for accounting as CSV, or as a zip of PDFs
+// with format=zip. from and to are inclusive YYYY-MM-DD dates on the issue date.
+// Paid payments without an invoice are invoiced first so the export is complete.
+func (h *AdminHandler) ExportInvoices(c *gin.Context) {
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+		return
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+		return
+	}
+	if to != nil {
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or zip"})
+		return
+	}
+
+	if err := h.documentService.IssueOutstandingInvoices(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoices"})
+		return
+	}
+
+	invoices, err := h.documentService.ListInvoices(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+		return
+	}
+
+	filename := "invoices_" + time.Now().Format("20060102")
+	if format == "zip" {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+		err = h.documentService.WriteInvoicesZip(c.Writer, invoices)
+	} else {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		err = h.documentService.WriteInvoicesCSV(c.Writer, invoices)
+	}
+	if err != nil {
+		// Headers are already sent; all we can do is abort the download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// parseDateQuery parses an optional YYYY-MM-DD query parameter
+func parseDateQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+)
+
+// PricingPlanRequest represents pricing plan create/update input
+type PricingPlanRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Amount       int64  `json:"amount" binding:"gte=0"`
+	Currency     string `json:"currency" binding:"required,len=3"`
+	Credits      int    `json:"credits" binding:"gte=0"`
+	Unlimited    bool   `json:"unlimited"`
+	ValidityDays int    `json:"validity_days" binding:"gte=0"`
+	Active       bool   `json:"active"`
+	SortOrder    int    `json:"sort_order"`
+}
+
+func (req *PricingPlanRequest) apply(plan *models.PricingPlan) {
+	plan.Name = req.Name
+	plan.Description = req.Description
+	plan.Amount = req.Amount
+	plan.Currency = strings.ToLower(req.Currency)
+	plan.Credits = req.Credits
+	plan.Unlimited = req.Unlimited
+	plan.ValidityDays = req.ValidityDays
+	plan.Active = req.Active
+	plan.SortOrder = req.SortOrder
+}
+
+func (req *PricingPlanRequest) validate() string {
+	if !req.Unlimited && req.Credits == 0 {
+		return "Plans must include credits or be unlimited"
+	}
+	if req.Unlimited && req.ValidityDays == 0 {
+		return "Unlimited plans require a validity period"
+	}
+	return ""
+}
+
+// ListPricingPlans returns all pricing plans, including inactive ones
+func (h *AdminHandler) ListPricingPlans(c *gin.Context) {
+	db := database.GetDB()
+
+	var plans []models.PricingPlan
+	if err := db.Order("sort_order ASC, amount ASC").Find(&plans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// CreatePricingPlan creates a new pricing plan
+func (h *AdminHandler) CreatePricingPlan(c *gin.Context) {
+	var req PricingPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	plan := &models.PricingPlan{}
+	req.apply(plan)
+
+	db := database.GetDB()
+	if err := db.Create(plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create plan"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Plan created successfully",
+		"plan":    plan,
+	})
+}
+
+// UpdatePricingPlan updates a pricing plan. Existing payments keep the terms they were bought with.
+func (h *AdminHandler) UpdatePricingPlan(c *gin.Context) {
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	var req PricingPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	db := database.GetDB()
+
+	var plan models.PricingPlan
+	if err := db.First(&plan, "id = ?", planID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+		return
+	}
+
+	// Keep at least one plan on sale
+	if plan.Active && !req.Active {
+		var remaining int64
+		db.Model(&models.PricingPlan{}).Where("active = ? AND id <> ?", true, planID).Count(&remaining)
+		if remaining == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot deactivate the last active plan"})
+			return
+		}
+	}
+
+	req.apply(&plan)
+
+	if err := db.Save(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Plan updated successfully",
+		"plan":    plan,
+	})
+}
+
+// DeletePricingPlan retires a pricing plan
+func (h *AdminHandler) DeletePricingPlan(c *gin.Context) {
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	db := database.GetDB()
+
+	// Keep at least one plan on sale
+	var remaining int64
+	db.Model(&models.PricingPlan{}).Where("active = ? AND id <> ?", true, planID).Count(&remaining)
+	if remaining == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete the last active plan"})
+		return
+	}
+
+	if err := db.Delete(&models.PricingPlan{}, "id = ?", planID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Plan deleted successfully"})
+}
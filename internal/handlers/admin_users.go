@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/middleware"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+// SuspendUserRequest gives the reason for a suspension
+type SuspendUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SuspendUser stops a user from signing in or using the API
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.accountService.Suspend(adminID, userID, req.Reason, c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account suspended",
+		"user":    user.ToResponse(),
+	})
+}
+
+// UnsuspendUser lets a suspended user back in
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.accountService.Unsuspend(adminID, userID, c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account unsuspended",
+		"user":    user.ToResponse(),
+	})
+}
+
+// ChangeUserRoleRequest names a user's new role
+type ChangeUserRoleRequest struct {
+	Role models.UserRole `json:"role" binding:"required"`
+}
+
+// ChangeUserRole moves a user to another role and signs them out
+func (h *AdminHandler) ChangeUserRole(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var req ChangeUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.accountService.ChangeRole(adminID, userID, req.Role, c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role changed",
+		"user":    user.ToResponse(),
+	})
+}
+
+// ForcePasswordReset clears a user's password, signs them out and emails
+// them a link to choose a new one
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	user, token, err := h.accountService.ForcePasswordReset(adminID, userID, c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	go h.emailService.SendPasswordResetEmail(user, token)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset link sent to " + user.Email})
+}
+
+// RevokeUserSessions signs a user out everywhere
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.accountService.RevokeSessions(adminID, userID, c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d sessions revoked", revoked),
+		"revoked": revoked,
+	})
+}
+
+// DeleteUser anonymises and deletes a user's account
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.Delete(adminID, userID, c.ClientIP()); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// ImpersonateUserRequest explains why an admin needs to see the platform as
+// the user. Impersonation is read-only unless AllowWrites is set.
+type ImpersonateUserRequest struct {
+	Reason      string `json:"reason" binding:"required"`
+	AllowWrites bool   `json:"allow_writes"`
+}
+
+// ImpersonateUser issues a short-lived token for seeing the platform as the
+// user, and tells the user by email
+func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
+	adminID, userID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	var req ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impersonation, err := h.accountService.Impersonate(adminID, userID, req.Reason, req.AllowWrites, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	if admin, err := h.authService.GetUserByID(adminID); err == nil {
+		go h.emailService.SendImpersonationNotification(impersonation.User, admin, req.Reason, req.AllowWrites, impersonation.ExpiresAt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":         impersonation.User.ToResponse(),
+		"token":        impersonation.AccessToken,
+		"expires_at":   impersonation.ExpiresAt,
+		"impersonated": true,
+		"read_only":    !impersonation.AllowWrites,
+	})
+}
+
+// adminTarget returns the acting admin and the user in the URL, and responds
+// if either is missing
+func adminTarget(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return adminID, userID, true
+}
+
+// respondAccountError maps account service errors to responses
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCannotManageSelf),
+		errors.Is(err, services.ErrCannotImpersonateAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadySuspended),
+		errors.Is(err, services.ErrNotSuspended),
+		errors.Is(err, services.ErrRoleUnchanged),
+		errors.Is(err, services.ErrRoleChangeBlocked),
+		errors.Is(err, services.ErrLastOrganizationOwner),
+		errors.Is(err, services.ErrAccountSuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidRole),
+		errors.Is(err, services.ErrImpersonationReason):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+	}
+}
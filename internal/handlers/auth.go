@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/middleware"
 	"github.com/ukuvago/angel-platform/internal/models"
@@ -13,15 +19,26 @@ import (
 type AuthHandler struct {
 	authService  *services.AuthService
 	emailService *services.EmailService
+	loginLimiter *services.LoginLimiter
 }
 
-func NewAuthHandler(authService *services.AuthService, emailService *services.EmailService) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, emailService *services.EmailService, loginLimiter *services.LoginLimiter) *AuthHandler {
 	return &AuthHandler{
 		authService:  authService,
 		emailService: emailService,
+		loginLimiter: loginLimiter,
 	}
 }
 
+// Password reset emails an address, or all addresses from one IP, may request per hour
+const (
+	passwordResetLimit   = 5
+	passwordResetIPLimit = 20
+)
+
+// Verification emails a user may request per hour
+const verificationResendLimit = 3
+
 // RegisterRequest represents registration input
 type RegisterRequest struct {
 	Email       string          `json:"email" binding:"required,email"`
@@ -53,19 +70,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	h.authService.UpdateUser(user)
 
 	// Send verification email
-	go h.emailService.SendVerificationEmail(user)
+	if token, err := h.authService.IssueVerifyToken(user); err == nil {
+		go h.emailService.SendVerificationEmail(user, token)
+	}
 
-	// Generate token
-	token, err := h.authService.GenerateToken(user)
+	// Sign the new user in
+	tokens, err := h.authService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Registration successful. Please check your email to verify your account.",
-		"user":    user.ToResponse(),
-		"token":   token,
+		"message":       "Registration successful. Please check your email to verify your account.",
+		"user":          user.ToResponse(),
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
 	})
 }
 
@@ -83,18 +104,120 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.authService.Login(req.Email, req.Password)
+	if wait := h.loginLimiter.Check(req.Email, c.ClientIP()); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	user, err := h.authService.Authenticate(req.Email, req.Password)
+	if errors.Is(err, services.ErrAccountSuspended) {
+		respondAccountSuspended(c)
+		return
+	}
 	if err != nil {
+		h.loginLimiter.Failure(req.Email, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
+	// Users with 2FA finish signing in at /auth/2fa/verify. Their failures
+	// are only forgotten once the second factor is right too.
+	if user.TOTPEnabled {
+		challenge, err := h.authService.IssueChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+	h.loginLimiter.Success(req.Email)
+
+	tokens, err := h.authService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          user.ToResponse(),
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
+
+// RefreshRequest represents token refresh input
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh swaps a refresh token for a new access and refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.authService.RefreshSession(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrAccountSuspended) {
+		respondAccountSuspended(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"user":  user.ToResponse(),
-		"token": token,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
 	})
 }
 
+// Logout ends the current session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(c)
+
+	if err := h.authService.RevokeSession(userID, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll ends every session of the current user, on all devices
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(userID, uuid.Nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out on all devices"})
+}
+
 // GetCurrentUser returns the current authenticated user
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
@@ -128,6 +251,40 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
 }
 
+// ResendVerification emails the current user a new verification link
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	if wait := h.loginLimiter.Throttle(verificationResendLimit, "verify:"+userID.String()); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, err := h.authService.IssueVerifyToken(user)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification link"})
+		return
+	}
+
+	go h.emailService.SendVerificationEmail(user, token)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
 // ForgotPasswordRequest represents forgot password input
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
@@ -141,18 +298,24 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.InitiatePasswordReset(req.Email)
+	if wait := h.loginLimiter.Throttle(passwordResetIPLimit, "reset-ip:"+c.ClientIP()); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+	if wait := h.loginLimiter.Throttle(passwordResetLimit, "reset:"+strings.ToLower(req.Email)); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	user, token, err := h.authService.InitiatePasswordReset(req.Email, c.ClientIP())
 	if err != nil {
 		// Don't reveal if email exists
 		c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a password reset link"})
 		return
 	}
 
-	if token != "" {
-		user, _ := h.authService.GetUserByEmail(req.Email)
-		if user != nil {
-			go h.emailService.SendPasswordResetEmail(user, token)
-		}
+	if user != nil {
+		go h.emailService.SendPasswordResetEmail(user, token)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a password reset link"})
@@ -294,10 +457,29 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+	sessionID, _ := middleware.GetSessionID(c)
+	if err := h.authService.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully. Other devices have been signed out."})
+}
+
+// tooManyAttempts tells a throttled client when to try again
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       services.ErrTooManyAttempts.Error(),
+		"retry_after": seconds,
+	})
+}
+
+// respondAccountSuspended tells a suspended user why they cannot sign in
+func respondAccountSuspended(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": services.ErrAccountSuspended.Error(),
+		"code":  "ACCOUNT_SUSPENDED",
+	})
 }
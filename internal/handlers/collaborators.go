@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/middleware"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+type CollaboratorHandler struct {
+	collaboratorService *services.CollaboratorService
+	authService         *services.AuthService
+	emailService        *services.EmailService
+}
+
+func NewCollaboratorHandler(collaboratorService *services.CollaboratorService, authService *services.AuthService, emailService *services.EmailService) *CollaboratorHandler {
+	return &CollaboratorHandler{
+		collaboratorService: collaboratorService,
+		authService:         authService,
+		emailService:        emailService,
+	}
+}
+
+// ListCollaborators returns a project's collaborators, and its pending
+// invitations to those who manage it
+func (h *CollaboratorHandler) ListCollaborators(c *gin.Context) {
+	project, ok := loadCollaborationProject(c, authz.ActionRead)
+	if !ok {
+		return
+	}
+
+	collaborators, invitations, err := h.collaboratorService.List(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborators"})
+		return
+	}
+
+	sub, _ := middleware.GetSubject(c)
+	if !authz.Can(sub, authz.ActionManage, authz.Project(project)) {
+		invitations = nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"collaborators": collaborators,
+		"invitations":   invitations,
+	})
+}
+
+// InviteCollaboratorRequest represents an invitation to collaborate on a project
+type InviteCollaboratorRequest struct {
+	Email      string                   `json:"email" binding:"required,email"`
+	Permission models.ProjectPermission `json:"permission"`
+}
+
+// InviteCollaborator emails an invitation to collaborate on the project
+func (h *CollaboratorHandler) InviteCollaborator(c *gin.Context) {
+	project, ok := loadCollaborationProject(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	var req InviteCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Permission == "" {
+		req.Permission = models.ProjectPermissionEditor
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	invitation, token, err := h.collaboratorService.Invite(userID, project.ID, req.Email, req.Permission)
+	if err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	if inviter, err := h.authService.GetUserByID(userID); err == nil {
+		go h.emailService.SendProjectInvitation(invitation.Email, inviter, project, invitation.Permission, token)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation sent to " + invitation.Email,
+		"invitation": invitation,
+	})
+}
+
+// RevokeCollaboratorInvitation cancels a pending invitation
+func (h *CollaboratorHandler) RevokeCollaboratorInvitation(c *gin.Context) {
+	project, ok := loadCollaborationProject(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	invitationID, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.collaboratorService.RevokeInvitation(project.ID, invitationID); err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// AcceptCollaboratorInvitation makes the current developer a collaborator on
+// the project they were invited to
+func (h *CollaboratorHandler) AcceptCollaboratorInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collaborator, err := h.collaboratorService.AcceptInvitation(userID, req.Token)
+	if err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "You are now collaborating on " + collaborator.Project.Title,
+		"collaborator": collaborator,
+	})
+}
+
+// UpdateCollaboratorRequest changes a collaborator's permission
+type UpdateCollaboratorRequest struct {
+	Permission models.ProjectPermission `json:"permission" binding:"required"`
+}
+
+// UpdateCollaborator changes what a collaborator may do on the project
+func (h *CollaboratorHandler) UpdateCollaborator(c *gin.Context) {
+	project, ok := loadCollaborationProject(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	collaboratorID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collaborator, err := h.collaboratorService.UpdatePermission(project.ID, collaboratorID, req.Permission)
+	if err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Collaborator updated",
+		"collaborator": collaborator,
+	})
+}
+
+// RemoveCollaborator takes a collaborator off the project, or lets a
+// collaborator leave it
+func (h *CollaboratorHandler) RemoveCollaborator(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	collaboratorID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	action := authz.ActionManage
+	if collaboratorID == userID {
+		action = authz.ActionRead
+	}
+	project, ok := loadCollaborationProject(c, action)
+	if !ok {
+		return
+	}
+
+	if err := h.collaboratorService.Remove(project.ID, collaboratorID); err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	message := "Collaborator removed"
+	if collaboratorID == userID {
+		message = "You have left the project"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// TransferProjectRequest names the collaborator to hand the project to
+type TransferProjectRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+}
+
+// TransferProject hands the project over to another collaborator
+func (h *CollaboratorHandler) TransferProject(c *gin.Context) {
+	project, ok := loadCollaborationProject(c, authz.ActionManage)
+	if !ok {
+		return
+	}
+
+	var req TransferProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if err := h.collaboratorService.TransferOwnership(userID, project.ID, req.UserID, c.ClientIP()); err != nil {
+		respondCollaboratorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project ownership transferred"})
+}
+
+// loadCollaborationProject loads the project in the URL if the current user
+// may perform the action on it, and responds otherwise
+func loadCollaborationProject(c *gin.Context, action authz.Action) (*models.Project, bool) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return nil, false
+	}
+
+	project, err := findManagedProject(c, projectID, action)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return project, true
+}
+
+// respondCollaboratorError maps collaborator service errors to responses
+func respondCollaboratorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCollaboratorNotFound),
+		errors.Is(err, services.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotProjectOwner),
+		errors.Is(err, services.ErrInvitationEmailMismatch),
+		errors.Is(err, services.ErrCollaboratorNotDeveloper):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyCollaborator),
+		errors.Is(err, services.ErrAlreadyProjectOwner),
+		errors.Is(err, services.ErrProjectOwnerFixed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidProjectPermission),
+		errors.Is(err, services.ErrInvalidInvitation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collaborators"})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/middleware"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+type KYCHandler struct {
+	kycService   *services.KYCService
+	emailService *services.EmailService
+}
+
+func NewKYCHandler(kycService *services.KYCService, emailService *services.EmailService) *KYCHandler {
+	return &KYCHandler{
+		kycService:   kycService,
+		emailService: emailService,
+	}
+}
+
+// SubmitKYC uploads an investor's identity and accreditation documents for review
+func (h *KYCHandler) SubmitKYC(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No documents provided"})
+		return
+	}
+
+	submission, err := h.kycService.Submit(userID, map[models.KYCDocumentType][]*multipart.FileHeader{
+		models.KYCDocumentIdentity:      form.File[string(models.KYCDocumentIdentity)],
+		models.KYCDocumentAccreditation: form.File[string(models.KYCDocumentAccreditation)],
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrKYCAlreadyApproved), errors.Is(err, services.ErrKYCPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrKYCDocumentsRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	go h.emailService.SendKYCStatusNotification(submission.Investor, submission)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Documents submitted for review",
+		"submission": submission,
+	})
+}
+
+// GetKYCStatus returns the investor's verification status and latest submission
+func (h *KYCHandler) GetKYCStatus(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	submission, err := h.kycService.LatestSubmission(userID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": models.KYCStatusNone})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     submission.Status,
+		"submission": submission,
+	})
+}
+
+// DownloadKYCDocument serves a document to the investor who uploaded it or an admin
+func (h *KYCHandler) DownloadKYCDocument(c *gin.Context) {
+	sub, exists := middleware.GetSubject(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	document, err := h.kycService.GetDocument(documentID)
+	if err != nil || document.Submission == nil || !authz.Can(sub, authz.ActionRead, authz.KYCSubmission(document.Submission)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	// Documents may be images, so never let the browser render them inline
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.FileAttachment(h.kycService.DocumentPath(document), document.FileName)
+}
+
+// ListKYCQueue returns submissions for admin review, pending ones by default
+func (h *KYCHandler) ListKYCQueue(c *gin.Context) {
+	status := models.KYCStatus(c.DefaultQuery("status", string(models.KYCStatusPending)))
+	if status == "all" {
+		status = ""
+	}
+
+	submissions, err := h.kycService.ListSubmissions(status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": submissions})
+}
+
+// GetKYCSubmission returns a submission with its investor and documents
+func (h *KYCHandler) GetKYCSubmission(c *gin.Context) {
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
+		return
+	}
+
+	submission, err := h.kycService.GetSubmission(submissionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission": submission})
+}
+
+// ReviewKYCRequest represents an admin decision on a submission
+type ReviewKYCRequest struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+// ReviewKYC approves or rejects a pending submission
+func (h *KYCHandler) ReviewKYC(c *gin.Context) {
+	adminID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	submissionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission ID"})
+		return
+	}
+
+	var req ReviewKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, err := h.kycService.Review(adminID, submissionID, req.Approved, req.Reason, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrKYCSubmissionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrKYCNotPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRejectionReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update submission"})
+		}
+		return
+	}
+
+	if submission.Investor != nil {
+		go h.emailService.SendKYCStatusNotification(submission.Investor, submission)
+	}
+
+	status := "approved"
+	if !req.Approved {
+		status = "rejected"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Submission " + status,
+		"submission": submission,
+	})
+}
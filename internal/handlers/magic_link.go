@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+// Sign-in links an address, or all addresses from one IP, may request per hour
+const (
+	magicLinkLimit   = 5
+	magicLinkIPLimit = 20
+)
+
+// MagicLinkRequest represents a request for an emailed sign-in link
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RequestMagicLink emails a single-use sign-in link
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if wait := h.loginLimiter.Throttle(magicLinkIPLimit, "magic-ip:"+c.ClientIP()); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+	if wait := h.loginLimiter.Throttle(magicLinkLimit, "magic:"+strings.ToLower(req.Email)); wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	user, token, err := h.authService.IssueMagicLink(req.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sign-in link"})
+		return
+	}
+	if user != nil {
+		go h.emailService.SendMagicLinkEmail(user, token)
+	}
+
+	// Don't reveal if email exists
+	c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a sign-in link"})
+}
+
+// ConsumeMagicLinkRequest represents a sign-in with an emailed link's token
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ConsumeMagicLink signs a user in with an emailed link. It only accepts POST,
+// and the emailed URL opens a page that asks the user to confirm, so mail
+// scanners that fetch links cannot use them up.
+func (h *AuthHandler) ConsumeMagicLink(c *gin.Context) {
+	var req ConsumeMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.ConsumeMagicLink(req.Token)
+	if errors.Is(err, services.ErrInvalidMagicLink) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrAccountSuspended) {
+		respondAccountSuspended(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	// The link stands in for the password; 2FA is still asked for
+	if user.TOTPEnabled {
+		challenge, err := h.authService.IssueChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	tokens, err := h.authService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":          user.ToResponse(),
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+	})
+}
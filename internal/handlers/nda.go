@@ -41,12 +41,7 @@ func (h *NDAHandler) GetNDAStatus(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	var nda models.NDA
-	err := db.Where("investor_id = ?", userID).
-		Order("signed_at DESC").
-		First(&nda).Error
-
+	nda, err := middleware.LatestNDA(c, userID)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"signed":  false,
@@ -56,11 +51,12 @@ func (h *NDAHandler) GetNDAStatus(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"signed":     true,
-		"valid":      nda.IsValid(),
-		"signed_at":  nda.SignedAt,
-		"expires_at": nda.ExpiresAt,
-		"version":    nda.Version,
+		"signed":          true,
+		"valid":           nda.IsValid(),
+		"signed_at":       nda.SignedAt,
+		"expires_at":      nda.ExpiresAt,
+		"version":         nda.Version,
+		"organization_id": nda.OrganizationID,
 	})
 }
 
@@ -97,10 +93,9 @@ func (h *NDAHandler) SignNDA(c *gin.Context) {
 		return
 	}
 
-	// Check if NDA already signed
+	// Check if NDA already signed, by the user or for their organisation
 	db := database.GetDB()
-	var existingNDA models.NDA
-	err = db.Where("investor_id = ?", userID).First(&existingNDA).Error
+	existingNDA, err := middleware.LatestNDA(c, userID)
 	if err == nil && existingNDA.IsValid() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You have already signed a valid NDA"})
 		return
@@ -113,22 +108,25 @@ func (h *NDAHandler) SignNDA(c *gin.Context) {
 	// Set expiration to 2 years from now
 	expiresAt := time.Now().AddDate(2, 0, 0)
 
+	// Owners and members sign for their whole organisation
 	nda := &models.NDA{
-		InvestorID:    userID,
-		SignatureData: req.SignatureData,
-		SignedName:    req.SignedName,
-		IPAddress:     c.ClientIP(),
-		UserAgent:     c.GetHeader("User-Agent"),
-		SignedAt:      time.Now(),
-		ExpiresAt:     &expiresAt,
-		Version:       "1.0",
-		DocumentHash:  documentHash,
+		InvestorID:     userID,
+		OrganizationID: services.ActingOrganizationID(userID),
+		SignatureData:  req.SignatureData,
+		SignedName:     req.SignedName,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.GetHeader("User-Agent"),
+		SignedAt:       time.Now(),
+		ExpiresAt:      &expiresAt,
+		Version:        "1.0",
+		DocumentHash:   documentHash,
 	}
 
 	if err := db.Create(nda).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save NDA"})
 		return
 	}
+	db.Preload("Organization").First(nda, "id = ?", nda.ID)
 
 	// Generate PDF
 	pdfPath, err := h.documentService.GenerateNDAPDF(nda, user)
@@ -153,25 +151,21 @@ func (h *NDAHandler) DownloadNDA(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	var nda models.NDA
-	err := db.Where("investor_id = ?", userID).
-		Order("signed_at DESC").
-		First(&nda).Error
-
+	nda, err := middleware.LatestNDA(c, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No signed NDA found"})
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID)
+	// An organisation's NDA shows the member who signed it
+	signer, err := h.authService.GetUserByID(nda.InvestorID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Generate fresh PDF
-	pdfPath, err := h.documentService.GenerateNDAPDF(&nda, user)
+	pdfPath, err := h.documentService.GenerateNDAPDF(nda, signer)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
 		return
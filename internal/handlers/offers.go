@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/middleware"
 	"github.com/ukuvago/angel-platform/internal/models"
@@ -66,10 +67,18 @@ func (h *OfferHandler) CreateOffer(c *gin.Context) {
 		return
 	}
 
+	// Owners and members make offers for their organisation, which has one
+	// pending offer per project at most
+	orgID := services.ActingOrganizationID(userID)
+	owner := db.Where("investor_id = ?", userID)
+	if orgID != nil {
+		owner = owner.Or("organization_id = ?", *orgID)
+	}
+
 	// Check for existing pending offer
 	var existingOffer models.InvestmentOffer
-	err := db.Where("investor_id = ? AND project_id = ? AND status = ?",
-		userID, req.ProjectID, models.OfferStatusPending).First(&existingOffer).Error
+	err := db.Where(owner).Where("project_id = ? AND status = ?",
+		req.ProjectID, models.OfferStatusPending).First(&existingOffer).Error
 	if err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "You already have a pending offer for this project"})
 		return
@@ -77,13 +86,14 @@ func (h *OfferHandler) CreateOffer(c *gin.Context) {
 
 	expiresAt := time.Now().AddDate(0, 0, 30)
 	offer := &models.InvestmentOffer{
-		InvestorID:    userID,
-		ProjectID:     req.ProjectID,
-		OfferAmount:   req.OfferAmount,
-		EquityRequest: req.EquityRequest,
-		TermsNotes:    req.TermsNotes,
-		Status:        models.OfferStatusPending,
-		ExpiresAt:     &expiresAt,
+		InvestorID:     userID,
+		OrganizationID: orgID,
+		ProjectID:      req.ProjectID,
+		OfferAmount:    req.OfferAmount,
+		EquityRequest:  req.EquityRequest,
+		TermsNotes:     req.TermsNotes,
+		Status:         models.OfferStatusPending,
+		ExpiresAt:      &expiresAt,
 	}
 
 	if err := db.Create(offer).Error; err != nil {
@@ -103,42 +113,38 @@ func (h *OfferHandler) CreateOffer(c *gin.Context) {
 	})
 }
 
-// GetMyOffers returns offers made by the investor or received by the developer
+// GetMyOffers returns the offers the user may read: those the investor or
+// their firm made, those received on the projects of the developer or their
+// startup, or all of them for admins
 func (h *OfferHandler) GetMyOffers(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
 	}
 
-	role, _ := middleware.GetUserRole(c)
 	db := database.GetDB()
 
-	var offers []models.InvestmentOffer
+	query := scopeQuery(db.Joins("JOIN projects ON projects.id = investment_offers.project_id"),
+		sub, authz.ActionRead, authz.KindOffer, map[authz.Relation]string{
+			authz.Owner:                    "investment_offers.investor_id",
+			authz.Counterparty:             "projects.developer_id",
+			authz.OwnerOrganization:        "investment_offers.organization_id",
+			authz.CounterpartyOrganization: "projects.organization_id",
+			authz.ProjectEditor:            collaboratingOn("projects.id", models.ProjectPermissionEditor),
+			authz.ProjectViewer:            collaboratingOn("projects.id", models.ProjectPermissionViewer),
+		})
 
-	if role == models.RoleInvestor {
-		// Get offers made by investor
-		if err := db.Where("investor_id = ?", userID).
-			Preload("Project").
-			Preload("Project.Category").
-			Preload("TermSheet").
-			Order("created_at DESC").
-			Find(&offers).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offers"})
-			return
-		}
-	} else if role == models.RoleDeveloper {
-		// Get offers received on developer's projects
-		if err := db.Joins("JOIN projects ON projects.id = investment_offers.project_id").
-			Where("projects.developer_id = ?", userID).
-			Preload("Project").
-			Preload("Investor").
-			Preload("TermSheet").
-			Order("investment_offers.created_at DESC").
-			Find(&offers).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offers"})
-			return
-		}
+	var offers []models.InvestmentOffer
+	if err := query.
+		Preload("Project").
+		Preload("Project.Category").
+		Preload("Investor").
+		Preload("TermSheet").
+		Order("investment_offers.created_at DESC").
+		Find(&offers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offers"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"offers": offers})
@@ -146,7 +152,7 @@ func (h *OfferHandler) GetMyOffers(c *gin.Context) {
 
 // GetOffer returns a specific offer
 func (h *OfferHandler) GetOffer(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -162,6 +168,7 @@ func (h *OfferHandler) GetOffer(c *gin.Context) {
 
 	var offer models.InvestmentOffer
 	if err := db.Preload("Project").
+		Preload("Project.Collaborators").
 		Preload("Investor").
 		Preload("TermSheet").
 		First(&offer, "id = ?", offerID).Error; err != nil {
@@ -169,13 +176,7 @@ func (h *OfferHandler) GetOffer(c *gin.Context) {
 		return
 	}
 
-	// Verify user has access
-	role, _ := middleware.GetUserRole(c)
-	if role == models.RoleInvestor && offer.InvestorID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
-	if role == models.RoleDeveloper && offer.Project.DeveloperID != userID {
+	if !authz.Can(sub, authz.ActionRead, authz.Offer(&offer)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -193,7 +194,7 @@ type RespondOfferRequest struct {
 
 // RespondToOffer accepts or rejects an offer (developer only)
 func (h *OfferHandler) RespondToOffer(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -214,13 +215,13 @@ func (h *OfferHandler) RespondToOffer(c *gin.Context) {
 	db := database.GetDB()
 
 	var offer models.InvestmentOffer
-	if err := db.Preload("Project").Preload("Investor").First(&offer, "id = ?", offerID).Error; err != nil {
+	if err := db.Preload("Project.Collaborators").Preload("Investor").First(&offer, "id = ?", offerID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
 		return
 	}
 
-	// Verify developer owns the project
-	if offer.Project.DeveloperID != userID {
+	// Only the project's developer responds
+	if !authz.Can(sub, authz.ActionRespond, authz.Offer(&offer)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -283,7 +284,7 @@ func (h *OfferHandler) RespondToOffer(c *gin.Context) {
 
 // WithdrawOffer allows an investor to withdraw their offer
 func (h *OfferHandler) WithdrawOffer(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -298,7 +299,7 @@ func (h *OfferHandler) WithdrawOffer(c *gin.Context) {
 	db := database.GetDB()
 
 	var offer models.InvestmentOffer
-	if err := db.First(&offer, "id = ? AND investor_id = ?", offerID, userID).Error; err != nil {
+	if err := db.First(&offer, "id = ?", offerID).Error; err != nil || !authz.Can(sub, authz.ActionWithdraw, authz.Offer(&offer)) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
 		return
 	}
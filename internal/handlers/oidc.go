@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+// oidcStateCookie ties a provider sign-in to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	config      *config.Config
+	oidcService *services.OIDCService
+	authService *services.AuthService
+}
+
+func NewOIDCHandler(cfg *config.Config, oidcService *services.OIDCService, authService *services.AuthService) *OIDCHandler {
+	return &OIDCHandler{
+		config:      cfg,
+		oidcService: oidcService,
+		authService: authService,
+	}
+}
+
+// ListProviders returns the identity providers users can sign in with
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oidcService.Providers()})
+}
+
+// Login sends the browser to the provider's sign-in page. An optional role
+// query parameter creates an account with that role if none matches.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.oidcService.StartLogin(c.Param("provider"), models.UserRole(c.Query("role")))
+	if errors.Is(err, services.ErrUnknownOIDCProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("OIDC: starting %s sign-in: %v", c.Param("provider"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, 600, "/api/auth/oidc", "", strings.HasPrefix(h.config.AppURL, "https://"), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback finishes a provider sign-in and hands the result to the frontend
+// in the URL fragment, which browsers do not send to servers
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", strings.HasPrefix(h.config.AppURL, "https://"), true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		h.finish(c, url.Values{"error": {"Sign-in was cancelled"}})
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || state != cookieState {
+		h.finish(c, url.Values{"error": {services.ErrInvalidOIDCState.Error()}})
+		return
+	}
+
+	user, err := h.oidcService.CompleteLogin(c.Param("provider"), state, c.Query("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidOIDCState),
+			errors.Is(err, services.ErrUnknownOIDCProvider),
+			errors.Is(err, services.ErrInvalidIDToken),
+			errors.Is(err, services.ErrOIDCEmailNotVerified),
+			errors.Is(err, services.ErrOIDCRoleRequired),
+			errors.Is(err, services.ErrAccountSuspended):
+			h.finish(c, url.Values{"error": {err.Error()}})
+		default:
+			log.Printf("OIDC: completing %s sign-in: %v", c.Param("provider"), err)
+			h.finish(c, url.Values{"error": {"Sign-in failed, please try again"}})
+		}
+		return
+	}
+
+	// The provider stands in for the password; 2FA is still asked for
+	if user.TOTPEnabled {
+		challenge, err := h.authService.IssueChallenge(user)
+		if err != nil {
+			h.finish(c, url.Values{"error": {"Failed to start sign-in"}})
+			return
+		}
+		h.finish(c, url.Values{"challenge_token": {challenge}})
+		return
+	}
+
+	tokens, err := h.authService.StartSession(user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		h.finish(c, url.Values{"error": {"Failed to generate token"}})
+		return
+	}
+
+	h.finish(c, url.Values{
+		"token":         {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_at":    {tokens.ExpiresAt.Format(time.RFC3339)},
+	})
+}
+
+// finish redirects to the frontend's sign-in callback page
+func (h *OIDCHandler) finish(c *gin.Context, result url.Values) {
+	c.Redirect(http.StatusFound, h.config.AppURL+"/oidc-callback#"+result.Encode())
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/middleware"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+	authService         *services.AuthService
+	emailService        *services.EmailService
+}
+
+func NewOrganizationHandler(organizationService *services.OrganizationService, authService *services.AuthService, emailService *services.EmailService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		authService:         authService,
+		emailService:        emailService,
+	}
+}
+
+// CreateOrganizationRequest represents a new organisation
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateOrganization forms an investment firm or startup with the current user as its owner
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.organizationService.Create(userID, req.Name)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Organization created",
+		"organization": org,
+	})
+}
+
+// GetMyOrganization returns the current user's membership and organisation,
+// or null if they are not in one
+func (h *OrganizationHandler) GetMyOrganization(c *gin.Context) {
+	member := middleware.GetOrganizationMembership(c)
+	if member == nil {
+		c.JSON(http.StatusOK, gin.H{"membership": nil, "organization": nil})
+		return
+	}
+
+	org, err := h.organizationService.Get(member.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"membership": nil, "organization": nil})
+		return
+	}
+
+	// Only owners see who has been invited
+	if member.Role != models.OrganizationRoleOwner {
+		org.Invitations = nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"membership":   member,
+		"organization": org,
+	})
+}
+
+// GetOrganization returns an organisation to its members or an admin
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	sub, exists := middleware.GetSubject(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	if sub.OrganizationID != orgID && !authz.CanAll(sub, authz.ActionRead, authz.KindOrganization) {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOrganizationNotFound.Error()})
+		return
+	}
+
+	org, err := h.organizationService.Get(orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if sub.OrganizationID == orgID && sub.OrganizationRole != models.OrganizationRoleOwner {
+		org.Invitations = nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organization": org})
+}
+
+// InviteMemberRequest represents an invitation to join an organisation
+type InviteMemberRequest struct {
+	Email string                  `json:"email" binding:"required,email"`
+	Role  models.OrganizationRole `json:"role"`
+}
+
+// InviteMember emails an invitation to join the organisation
+func (h *OrganizationHandler) InviteMember(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.OrganizationRoleMember
+	}
+
+	invitation, token, err := h.organizationService.Invite(userID, orgID, req.Email, req.Role)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	inviter, err := h.authService.GetUserByID(userID)
+	if err == nil {
+		if org, err := h.organizationService.Get(orgID); err == nil {
+			go h.emailService.SendOrganizationInvitation(invitation.Email, inviter, org, invitation.Role, token)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation sent to " + invitation.Email,
+		"invitation": invitation,
+	})
+}
+
+// RevokeInvitation cancels a pending invitation
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	invitationID, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.organizationService.RevokeInvitation(userID, orgID, invitationID); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// AcceptInvitationRequest carries the token from an invitation email
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AcceptInvitation joins the organisation the current user was invited to
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.organizationService.AcceptInvitation(userID, req.Token)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "You have joined the organization",
+		"membership": member,
+	})
+}
+
+// UpdateMemberRequest changes a member's role
+type UpdateMemberRequest struct {
+	Role models.OrganizationRole `json:"role" binding:"required"`
+}
+
+// UpdateMember changes the role of a member of the organisation
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.organizationService.UpdateMemberRole(userID, orgID, memberID, req.Role)
+	if err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Member updated",
+		"membership": member,
+	})
+}
+
+// RemoveMember removes a member from the organisation, or lets a member leave
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(userID, orgID, memberID); err != nil {
+		respondOrganizationError(c, err)
+		return
+	}
+
+	message := "Member removed"
+	if memberID == userID {
+		message = "You have left the organization"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// respondOrganizationError maps organisation service errors to responses
+func respondOrganizationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound),
+		errors.Is(err, services.ErrInvitationNotFound),
+		errors.Is(err, services.ErrNotOrganizationMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotOrganizationOwner),
+		errors.Is(err, services.ErrInvitationEmailMismatch),
+		errors.Is(err, services.ErrOrganizationNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyInOrganization),
+		errors.Is(err, services.ErrLastOrganizationOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidOrganizationRole),
+		errors.Is(err, services.ErrInvalidInvitation),
+		errors.Is(err, services.ErrOrganizationNameRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+	}
+}
@@ -1,20 +1,51 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/middleware"
+	"github.com/ukuvago/angel-platform/internal/models"
 	"github.com/ukuvago/angel-platform/internal/services"
 )
 
 type PaymentHandler struct {
-	paymentService *services.PaymentService
+	paymentService  *services.PaymentService
+	documentService *services.DocumentService
 }
 
-func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
-	return &PaymentHandler{paymentService: paymentService}
+func NewPaymentHandler(paymentService *services.PaymentService, documentService *services.DocumentService) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService:  paymentService,
+		documentService: documentService,
+	}
+}
+
+// GetPlans returns the pricing plans available for purchase
+func (h *PaymentHandler) GetPlans(c *gin.Context) {
+	plans, err := h.paymentService.ListActivePlans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve plans"})
+		return
+	}
+
+	response := make([]models.PricingPlanResponse, 0, len(plans))
+	for _, p := range plans {
+		response = append(response, p.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": response})
+}
+
+// CreatePaymentIntentRequest represents payment creation input
+type CreatePaymentIntentRequest struct {
+	PlanID     *uuid.UUID `json:"plan_id"` // Defaults to the first active plan
+	CouponCode string     `json:"coupon_code"`
 }
 
 // CreatePaymentIntent creates a new payment intent for viewing projects
@@ -25,18 +56,31 @@ func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
 		return
 	}
 
-	payment, clientSecret, err := h.paymentService.CreatePaymentIntent(userID)
-	if err != nil {
+	var req CreatePaymentIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	payment, clientSecret, err := h.paymentService.CreatePaymentIntent(userID, req.PlanID, req.CouponCode)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"payment_id":    payment.ID,
+		"status":        payment.Status,
 		"client_secret": clientSecret,
+		"provider":      payment.Provider,
+		"checkout_url":  payment.CheckoutURL,
 		"amount":        payment.Amount,
+		"discount":      payment.DiscountAmount,
 		"currency":      payment.Currency,
 		"projects":      payment.ProjectsTotal,
+		"unlimited":     payment.Unlimited,
+		"plan_id":       payment.PlanID,
+		"description":   payment.Description,
 	})
 }
 
@@ -44,6 +88,7 @@ func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
 type ConfirmPaymentRequest struct {
 	PaymentID       uuid.UUID `json:"payment_id" binding:"required"`
 	StripePaymentID string    `json:"stripe_payment_id"`
+	Reference       string    `json:"reference"` // Provider reference for non-Stripe providers
 	DemoMode        bool      `json:"demo_mode"`
 }
 
@@ -61,41 +106,135 @@ func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
 		return
 	}
 
-	var payment *interface{}
+	var payment *models.Payment
 	var err error
-
 	if req.DemoMode {
-		// Demo mode confirmation
-		p, e := h.paymentService.DemoConfirmPayment(req.PaymentID)
-		if e != nil {
-			err = e
-		} else {
-			var temp interface{} = p
-			payment = &temp
-		}
+		payment, err = h.paymentService.DemoConfirmPayment(userID, req.PaymentID)
 	} else {
-		p, e := h.paymentService.ConfirmPayment(req.PaymentID, req.StripePaymentID)
-		if e != nil {
-			err = e
-		} else {
-			var temp interface{} = p
-			payment = &temp
+		intentID := req.StripePaymentID
+		if intentID == "" {
+			intentID = req.Reference
 		}
+		payment, err = h.paymentService.ConfirmPayment(userID, req.PaymentID, intentID)
+	}
+
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment confirmed successfully",
+		"payment": payment.ToResponse(),
+	})
+}
+
+// RequestRefundRequest represents an investor refund request
+type RequestRefundRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RequestRefund refunds the unused credits of one of the user's payments
+func (h *PaymentHandler) RequestRefund(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
 	}
 
+	paymentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req RequestRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	_ = userID // Validate payment belongs to user in production
+	payment, err := h.paymentService.RequestRefund(userID, paymentID, req.Reason)
+	if err != nil {
+		c.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Payment confirmed successfully",
-		"payment": payment,
+		"message": "Payment refunded successfully",
+		"payment": payment.ToResponse(),
 	})
 }
 
+// DownloadInvoice returns the invoice PDF of a paid payment: investors get their
+// own, admins any
+func (h *PaymentHandler) DownloadInvoice(c *gin.Context) {
+	sub, exists := middleware.GetSubject(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	db := database.GetDB()
+
+	var payment models.Payment
+	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if !authz.Can(sub, authz.ActionRead, authz.Payment(&payment)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	invoice, err := h.documentService.IssueInvoice(&payment)
+	if errors.Is(err, services.ErrInvoiceUnavailable) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+		return
+	}
+
+	pdfPath, err := h.documentService.GenerateInvoicePDF(invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+		return
+	}
+
+	c.FileAttachment(pdfPath, invoice.InvoiceNumber()+".pdf")
+}
+
+// paymentErrorStatus maps payment service errors to HTTP status codes
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound), errors.Is(err, services.ErrPlanNotFound),
+		errors.Is(err, services.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPaymentForbidden), errors.Is(err, services.ErrDemoModeDisabled),
+		errors.Is(err, services.ErrSelfRefundDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrPaymentAlreadyProcessed), errors.Is(err, services.ErrPaymentIntentMismatch),
+		errors.Is(err, services.ErrPaymentAmountMismatch),
+		errors.Is(err, services.ErrRefundNotAllowed), errors.Is(err, services.ErrCouponExhausted),
+		errors.Is(err, services.ErrCouponAlreadyUsed), errors.Is(err, services.ErrRefundInProgress):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentNotSuccessful):
+		return http.StatusPaymentRequired
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 // GetPaymentStatus returns the current payment status
 func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
@@ -147,6 +286,25 @@ func (h *PaymentHandler) GetPaymentHistory(c *gin.Context) {
 	})
 }
 
+// GetCreditLedger returns the history of the user's view credits
+func (h *PaymentHandler) GetCreditLedger(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	entries, err := h.paymentService.GetCreditLedger(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve credit history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
 // GetViewedProjects returns projects the user has viewed
 func (h *PaymentHandler) GetViewedProjects(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/middleware"
 	"github.com/ukuvago/angel-platform/internal/models"
 	"github.com/ukuvago/angel-platform/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectHandler struct {
@@ -76,52 +80,40 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 	if err := db.Preload("Category").
 		Preload("Images").
 		Preload("Developer").
+		Preload("Collaborators").
 		First(&project, "id = ?", projectID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
 
-	userID, exists := middleware.GetUserID(c)
-	role, _ := middleware.GetUserRole(c)
+	sub, exists := middleware.GetSubject(c)
+	res := authz.Project(&project)
 
-	// Developers can view their own projects
-	if role == models.RoleDeveloper && project.DeveloperID == userID {
-		c.JSON(http.StatusOK, gin.H{"project": project})
-		return
-	}
-
-	// Admins can view all projects
-	if role == models.RoleAdmin {
-		c.JSON(http.StatusOK, gin.H{"project": project})
-		return
-	}
-
-	// For investors: check if already viewed
-	if exists && role == models.RoleInvestor {
-		if h.paymentService.HasViewedProject(userID, projectID) {
-			// Already viewed, show full details
-			c.JSON(http.StatusOK, gin.H{"project": project})
-			return
-		}
+	// Investors unlock an approved project by spending a view credit, unless
+	// they or their organisation already have
+	if exists && sub.Role == models.RoleInvestor && project.Status == models.ProjectStatusApproved {
+		res.Unlocked = h.paymentService.HasViewedProject(sub.ID, projectID)
 
-		// Use a view credit
-		if err := h.paymentService.UseViewCredit(userID, projectID); err != nil {
-			// Return public info only
-			c.JSON(http.StatusOK, gin.H{
-				"project":        project.ToPublicInfo(),
-				"full_access":    false,
-				"payment_needed": true,
-				"error":          err.Error(),
-			})
-			return
+		// An admin seeing the platform as the investor must not spend their credits
+		_, impersonating := middleware.GetImpersonatorID(c)
+		if !res.Unlocked && !impersonating {
+			if err := h.paymentService.UseViewCredit(sub.ID, projectID); err != nil {
+				// Return public info only
+				c.JSON(http.StatusOK, gin.H{
+					"project":        project.ToPublicInfo(),
+					"full_access":    false,
+					"payment_needed": true,
+					"error":          err.Error(),
+				})
+				return
+			}
+			res.Unlocked = true
 		}
-
-		// Increment view count
-		db.Model(&project).Update("view_count", project.ViewCount+1)
 	}
 
-	// Check if public-only access
-	if !exists || project.Status != models.ProjectStatusApproved {
+	// Developers see their own projects, admins all of them, and investors
+	// the ones they unlocked
+	if !exists || !authz.Can(sub, authz.ActionView, res) {
 		c.JSON(http.StatusOK, gin.H{
 			"project":     project.ToPublicInfo(),
 			"full_access": false,
@@ -183,7 +175,7 @@ type TeamMemberInput struct {
 	IsLead     bool   `json:"is_lead"`
 }
 
-// CreateProject creates a new project (developer only)
+// CreateProject creates a new draft project owned by the current developer or admin
 func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -239,29 +231,33 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 
 	project := &models.Project{
-		ID:            projectID,
-		DeveloperID:   userID,
-		CategoryID:    categoryID,
-		Title:         req.Title,
-		Tagline:       req.Tagline,
-		Description:   req.Description,
-		PitchContent:  req.PitchContent,
-		Problem:       req.Problem,
-		Solution:      req.Solution,
-		TargetMarket:  req.TargetMarket,
-		BusinessModel: req.BusinessModel,
-		Traction:      req.Traction,
-		ContactEmail:  req.ContactEmail,
-		ContactPhone:  req.ContactPhone,
-		WebsiteURL:    req.WebsiteURL,
-		POCUrl:        req.POCUrl,
-		PitchDeck:     pitchDeckPath,
-		MinInvestment: req.MinInvestment,
-		MaxInvestment: req.MaxInvestment,
-		EquityOffered: req.EquityOffered,
-		ValuationCap:  req.ValuationCap,
-		Status:        models.ProjectStatusDraft,
-		TeamMembers:   teamMembers,
+		ID:             projectID,
+		DeveloperID:    userID,
+		OrganizationID: services.ActingOrganizationID(userID),
+		CategoryID:     categoryID,
+		Title:          req.Title,
+		Tagline:        req.Tagline,
+		Description:    req.Description,
+		PitchContent:   req.PitchContent,
+		Problem:        req.Problem,
+		Solution:       req.Solution,
+		TargetMarket:   req.TargetMarket,
+		BusinessModel:  req.BusinessModel,
+		Traction:       req.Traction,
+		ContactEmail:   req.ContactEmail,
+		ContactPhone:   req.ContactPhone,
+		WebsiteURL:     req.WebsiteURL,
+		POCUrl:         req.POCUrl,
+		PitchDeck:      pitchDeckPath,
+		MinInvestment:  req.MinInvestment,
+		MaxInvestment:  req.MaxInvestment,
+		EquityOffered:  req.EquityOffered,
+		ValuationCap:   req.ValuationCap,
+		Status:         models.ProjectStatusDraft,
+		TeamMembers:    teamMembers,
+		Collaborators: []models.ProjectCollaborator{
+			{UserID: userID, Permission: models.ProjectPermissionOwner},
+		},
 	}
 
 	db := database.GetDB()
@@ -270,13 +266,13 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		return
 	}
 
-	// Handle Multiple Images
-	form, _ := c.MultipartForm()
-	files := form.File["images"] // look for "images" key (multiple)
-	// Fallback to single "image" key if "images" is empty (backward compat)
-	if len(files) == 0 {
-		if f := form.File["image"]; len(f) > 0 {
-			files = f
+	// Handle Multiple Images (JSON requests carry none)
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["images"] // look for "images" key (multiple)
+		// Fallback to single "image" key if "images" is empty (backward compat)
+		if len(files) == 0 {
+			files = form.File["image"]
 		}
 	}
 
@@ -300,10 +296,10 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	})
 }
 
-// UpdateProject updates a project (developer only, draft/rejected status)
+// UpdateProject updates a project. Developers may only edit their own draft or
+// rejected projects; admins may edit any project.
 func (h *ProjectHandler) UpdateProject(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
-	role, _ := middleware.GetUserRole(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -317,20 +313,14 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 
 	db := database.GetDB()
 
-	var project models.Project
-	// Admin can edit ANY project; Developer can only edit their own
-	query := db.Where("id = ?", projectID)
-	if role != models.RoleAdmin {
-		query = query.Where("developer_id = ?", userID)
-	}
-
-	if err := query.First(&project).Error; err != nil {
+	project, err := findManagedProject(c, projectID, authz.ActionUpdate)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
 
 	// Admin can edit project in any status. Developer restricted to Draft/Rejected.
-	if role != models.RoleAdmin && project.Status != models.ProjectStatusDraft && project.Status != models.ProjectStatusRejected {
+	if !authz.CanAll(sub, authz.ActionUpdate, authz.KindProject) && project.Status != models.ProjectStatusDraft && project.Status != models.ProjectStatusRejected {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot edit approved or pending projects"})
 		return
 	}
@@ -375,7 +365,7 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	project.EquityOffered = req.EquityOffered
 	project.ValuationCap = req.ValuationCap
 
-	if err := db.Save(&project).Error; err != nil {
+	if err := db.Omit(clause.Associations).Save(project).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
 		return
 	}
@@ -386,9 +376,9 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	})
 }
 
-// SubmitProject submits a project for review
+// SubmitProject submits a draft or rejected project for review
 func (h *ProjectHandler) SubmitProject(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	_, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -402,8 +392,8 @@ func (h *ProjectHandler) SubmitProject(c *gin.Context) {
 
 	db := database.GetDB()
 
-	var project models.Project
-	if err := db.First(&project, "id = ? AND developer_id = ?", projectID, userID).Error; err != nil {
+	project, err := findManagedProject(c, projectID, authz.ActionSubmit)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
@@ -414,7 +404,7 @@ func (h *ProjectHandler) SubmitProject(c *gin.Context) {
 	}
 
 	project.Status = models.ProjectStatusPending
-	if err := db.Save(&project).Error; err != nil {
+	if err := db.Omit(clause.Associations).Save(project).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit project"})
 		return
 	}
@@ -427,7 +417,7 @@ func (h *ProjectHandler) SubmitProject(c *gin.Context) {
 
 // UploadProjectImage uploads an image for a project
 func (h *ProjectHandler) UploadProjectImage(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	_, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -441,8 +431,7 @@ func (h *ProjectHandler) UploadProjectImage(c *gin.Context) {
 
 	db := database.GetDB()
 
-	var project models.Project
-	if err := db.First(&project, "id = ? AND developer_id = ?", projectID, userID).Error; err != nil {
+	if _, err := findManagedProject(c, projectID, authz.ActionUpdate); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
@@ -495,7 +484,7 @@ func (h *ProjectHandler) UploadProjectImage(c *gin.Context) {
 
 // DeleteProjectImage deletes an image from a project
 func (h *ProjectHandler) DeleteProjectImage(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	_, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -516,8 +505,7 @@ func (h *ProjectHandler) DeleteProjectImage(c *gin.Context) {
 	db := database.GetDB()
 
 	// Verify project ownership
-	var project models.Project
-	if err := db.First(&project, "id = ? AND developer_id = ?", projectID, userID).Error; err != nil {
+	if _, err := findManagedProject(c, projectID, authz.ActionUpdate); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
@@ -540,9 +528,25 @@ func (h *ProjectHandler) DeleteProjectImage(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
 }
 
-// GetMyProjects returns the developer's projects
+// findManagedProject loads a project the current user may perform the action
+// on. Projects they may not touch are reported as not found.
+func findManagedProject(c *gin.Context, projectID uuid.UUID, action authz.Action) (*models.Project, error) {
+	sub, _ := middleware.GetSubject(c)
+
+	var project models.Project
+	if err := database.GetDB().Preload("Collaborators").First(&project, "id = ?", projectID).Error; err != nil {
+		return nil, err
+	}
+	if !authz.Can(sub, action, authz.Project(&project)) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &project, nil
+}
+
+// GetMyProjects returns the projects the developer owns or collaborates on,
+// and their startup's
 func (h *ProjectHandler) GetMyProjects(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -550,10 +554,17 @@ func (h *ProjectHandler) GetMyProjects(c *gin.Context) {
 
 	db := database.GetDB()
 
+	collaborating := db.Model(&models.ProjectCollaborator{}).Select("project_id").Where("user_id = ?", sub.ID)
+	query := db.Where("developer_id = ? OR id IN (?)", sub.ID, collaborating)
+	if sub.OrganizationID != uuid.Nil {
+		query = db.Where("developer_id = ? OR id IN (?) OR organization_id = ?", sub.ID, collaborating, sub.OrganizationID)
+	}
+
 	var projects []models.Project
-	if err := db.Where("developer_id = ?", userID).
+	if err := query.
 		Preload("Category").
 		Preload("Images").
+		Preload("Collaborators", "user_id = ?", sub.ID).
 		Order("created_at DESC").
 		Find(&projects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
@@ -563,7 +574,8 @@ func (h *ProjectHandler) GetMyProjects(c *gin.Context) {
 	// Count offers for each project
 	type ProjectWithOffers struct {
 		models.Project
-		PendingOffers int `json:"pending_offers"`
+		Permission    models.ProjectPermission `json:"permission,omitempty"` // Empty when only shared through the startup
+		PendingOffers int                      `json:"pending_offers"`
 	}
 
 	var result []ProjectWithOffers
@@ -573,8 +585,14 @@ func (h *ProjectHandler) GetMyProjects(c *gin.Context) {
 			Where("project_id = ? AND status = ?", p.ID, models.OfferStatusPending).
 			Count(&count)
 
+		var permission models.ProjectPermission
+		if len(p.Collaborators) > 0 {
+			permission = p.Collaborators[0].Permission
+		}
+
 		result = append(result, ProjectWithOffers{
 			Project:       p,
+			Permission:    permission,
 			PendingOffers: int(count),
 		})
 	}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// scopeQuery restricts a list query to the records the subject may perform the
+// action on. columns names the column holding the user or organisation ID for
+// each relation, or a condition with one placeholder for the ID.
+func scopeQuery(query *gorm.DB, sub authz.Subject, action authz.Action, kind authz.Kind, columns map[authz.Relation]string) *gorm.DB {
+	var conditions []string
+	var args []interface{}
+	for _, rel := range authz.Scope(sub, action, kind) {
+		if rel == authz.Any {
+			return query
+		}
+		column, ok := columns[rel]
+		if id := sub.PartyID(rel); ok && id != uuid.Nil {
+			if !strings.Contains(column, "?") {
+				column += " = ?"
+			}
+			conditions = append(conditions, column)
+			args = append(args, id)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where(strings.Join(conditions, " OR "), args...)
+}
+
+// collaboratingOn is a scopeQuery condition matching records whose project
+// column holds a project the user collaborates on with the permission
+func collaboratingOn(column string, permission models.ProjectPermission) string {
+	return column + " IN (SELECT project_id FROM project_collaborators WHERE permission = '" + string(permission) + "' AND user_id = ?)"
+}
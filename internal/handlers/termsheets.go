@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/middleware"
 	"github.com/ukuvago/angel-platform/internal/models"
@@ -27,7 +29,7 @@ func NewTermSheetHandler(documentService *services.DocumentService, emailService
 
 // GetTermSheet returns a specific term sheet
 func (h *TermSheetHandler) GetTermSheet(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -44,19 +46,14 @@ func (h *TermSheetHandler) GetTermSheet(c *gin.Context) {
 	var termSheet models.TermSheet
 	if err := db.Preload("Offer").
 		Preload("Offer.Project").
+		Preload("Offer.Project.Collaborators").
 		Preload("Offer.Investor").
 		First(&termSheet, "id = ?", termSheetID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Term sheet not found"})
 		return
 	}
 
-	// Verify user has access
-	role, _ := middleware.GetUserRole(c)
-	if role == models.RoleInvestor && termSheet.Offer.InvestorID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
-	if role == models.RoleDeveloper && termSheet.Offer.Project.DeveloperID != userID {
+	if !authz.Can(sub, authz.ActionRead, authz.TermSheet(&termSheet)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -64,41 +61,36 @@ func (h *TermSheetHandler) GetTermSheet(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"term_sheet": termSheet})
 }
 
-// GetMyTermSheets returns term sheets for the current user
+// GetMyTermSheets returns the term sheets the current user may read
 func (h *TermSheetHandler) GetMyTermSheets(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
 	}
 
-	role, _ := middleware.GetUserRole(c)
 	db := database.GetDB()
 
-	var termSheets []models.TermSheet
+	query := scopeQuery(db.Joins("JOIN investment_offers ON investment_offers.id = term_sheets.offer_id").
+		Joins("JOIN projects ON projects.id = investment_offers.project_id"),
+		sub, authz.ActionRead, authz.KindTermSheet, map[authz.Relation]string{
+			authz.Owner:                    "investment_offers.investor_id",
+			authz.Counterparty:             "projects.developer_id",
+			authz.OwnerOrganization:        "investment_offers.organization_id",
+			authz.CounterpartyOrganization: "projects.organization_id",
+			authz.ProjectEditor:            collaboratingOn("projects.id", models.ProjectPermissionEditor),
+			authz.ProjectViewer:            collaboratingOn("projects.id", models.ProjectPermissionViewer),
+		})
 
-	if role == models.RoleInvestor {
-		if err := db.Joins("JOIN investment_offers ON investment_offers.id = term_sheets.offer_id").
-			Where("investment_offers.investor_id = ?", userID).
-			Preload("Offer").
-			Preload("Offer.Project").
-			Order("term_sheets.created_at DESC").
-			Find(&termSheets).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch term sheets"})
-			return
-		}
-	} else if role == models.RoleDeveloper {
-		if err := db.Joins("JOIN investment_offers ON investment_offers.id = term_sheets.offer_id").
-			Joins("JOIN projects ON projects.id = investment_offers.project_id").
-			Where("projects.developer_id = ?", userID).
-			Preload("Offer").
-			Preload("Offer.Project").
-			Preload("Offer.Investor").
-			Order("term_sheets.created_at DESC").
-			Find(&termSheets).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch term sheets"})
-			return
-		}
+	var termSheets []models.TermSheet
+	if err := query.
+		Preload("Offer").
+		Preload("Offer.Project").
+		Preload("Offer.Investor").
+		Order("term_sheets.created_at DESC").
+		Find(&termSheets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch term sheets"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"term_sheets": termSheets})
@@ -111,7 +103,7 @@ type SignTermSheetRequest struct {
 
 // SignTermSheet signs a term sheet
 func (h *TermSheetHandler) SignTermSheet(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -129,9 +121,16 @@ func (h *TermSheetHandler) SignTermSheet(c *gin.Context) {
 		return
 	}
 
-	termSheet, err := h.documentService.SignTermSheet(termSheetID, userID, req.SignatureData, c.ClientIP())
+	termSheet, err := h.documentService.SignTermSheet(termSheetID, sub, req.SignatureData, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrTermSheetNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrNotTermSheetParty):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -162,7 +161,7 @@ func (h *TermSheetHandler) SignTermSheet(c *gin.Context) {
 
 // DownloadTermSheet downloads the term sheet PDF
 func (h *TermSheetHandler) DownloadTermSheet(c *gin.Context) {
-	userID, exists := middleware.GetUserID(c)
+	sub, exists := middleware.GetSubject(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -179,15 +178,14 @@ func (h *TermSheetHandler) DownloadTermSheet(c *gin.Context) {
 	var termSheet models.TermSheet
 	if err := db.Preload("Offer").
 		Preload("Offer.Project").
+		Preload("Offer.Project.Collaborators").
 		Preload("Offer.Investor").
 		First(&termSheet, "id = ?", termSheetID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Term sheet not found"})
 		return
 	}
 
-	// Verify user has access
-	role, _ := middleware.GetUserRole(c)
-	if role == models.RoleInvestor && termSheet.Offer.InvestorID != userID {
+	if !authz.Can(sub, authz.ActionRead, authz.TermSheet(&termSheet)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -195,11 +193,6 @@ func (h *TermSheetHandler) DownloadTermSheet(c *gin.Context) {
 	var developer models.User
 	db.First(&developer, "id = ?", termSheet.Offer.Project.DeveloperID)
 
-	if role == models.RoleDeveloper && developer.ID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
-
 	// Generate fresh PDF
 	pdfPath, err := h.documentService.GenerateSAFENotePDF(&termSheet, termSheet.Offer, termSheet.Offer.Investor, &developer, termSheet.Offer.Project)
 	if err != nil {
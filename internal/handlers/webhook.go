@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ukuvago/angel-platform/internal/services"
+)
+
+// maxWebhookBodyBytes caps the payload size accepted from payment providers
+const maxWebhookBodyBytes = 65536
+
+type WebhookHandler struct {
+	paymentService *services.PaymentService
+}
+
+func NewWebhookHandler(paymentService *services.PaymentService) *WebhookHandler {
+	return &WebhookHandler{paymentService: paymentService}
+}
+
+// HandleWebhook receives signed events from the payment provider named in the
+// path and updates payment state
+func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
+	providerName := c.Param("provider")
+	provider := h.paymentService.Provider(providerName)
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	event, err := h.paymentService.ParseWebhookEvent(providerName, payload, c.GetHeader(provider.SignatureHeader()))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Webhooks not configured"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	if err := h.paymentService.HandleWebhookEvent(providerName, event); err != nil {
+		// Non-2xx makes the provider retry the delivery
+		log.Printf("Failed to process %s event %s (%s): %v", providerName, event.ID, event.Type, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
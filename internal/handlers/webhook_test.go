@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v76/webhook"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/handlers"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+const stripeWebhookSecret = "whsec_fixture"
+
+// signStripe signs a payload the way Stripe does, with the given secret
+func signStripe(payload []byte, secret string) string {
+	return webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: time.Now(),
+	}).Header
+}
+
+func TestStripeWebhook(t *testing.T) {
+	cfg := testutil.Database(t)
+	paymentService := services.NewPaymentServiceWithProvider(cfg, services.NewStripeProvider("sk_test_fixture", stripeWebhookSecret))
+	router := gin.New()
+	router.POST("/api/webhooks/:provider", handlers.NewWebhookHandler(paymentService).HandleWebhook)
+
+	investor := testutil.User(t, models.RoleInvestor)
+	payment := &models.Payment{
+		InvestorID:      investor.ID,
+		Amount:          50000,
+		Currency:        "usd",
+		StripePaymentID: "pi_3PqB2dKx8ZfYqW1r0N7kLm2p",
+		Provider:        "stripe",
+		Status:          models.PaymentStatusPending,
+		ProjectsTotal:   4,
+	}
+	if err := database.GetDB().Create(payment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, err := os.ReadFile(filepath.Join("testdata", "stripe", "payment_intent_succeeded.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(strings.NewReplacer("PAYMENT_ID", payment.ID.String(), "INVESTOR_ID", investor.ID.String()).Replace(string(fixture)))
+
+	deliver := func(provider, header, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks/"+provider, bytes.NewReader(payload))
+		req.Header.Set(header, signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	status := func() models.PaymentStatus {
+		var stored models.Payment
+		database.GetDB().First(&stored, "id = ?", payment.ID)
+		return stored.Status
+	}
+	credits := func() int {
+		balance, _ := paymentService.CreditBalance(database.GetDB(), payment.ID)
+		return balance
+	}
+
+	// Unknown providers and bad signatures change nothing
+	if code := deliver("paypal", "Stripe-Signature", signStripe(payload, stripeWebhookSecret)); code != http.StatusNotFound {
+		t.Errorf("unknown provider: %d, want 404", code)
+	}
+	if code := deliver("stripe", "Stripe-Signature", signStripe(payload, "whsec_other")); code != http.StatusBadRequest {
+		t.Errorf("bad signature: %d, want 400", code)
+	}
+	if code := deliver("stripe", "Stripe-Signature", ""); code != http.StatusBadRequest {
+		t.Errorf("missing signature: %d, want 400", code)
+	}
+	if got := status(); got != models.PaymentStatusPending {
+		t.Fatalf("rejected webhooks left the payment %s", got)
+	}
+
+	if code := deliver("stripe", "Stripe-Signature", signStripe(payload, stripeWebhookSecret)); code != http.StatusOK {
+		t.Fatalf("valid webhook: %d, want 200", code)
+	}
+	if got := status(); got != models.PaymentStatusCompleted || credits() != 4 {
+		t.Fatalf("valid webhook left the payment %s with %d credits", got, credits())
+	}
+
+	// A replayed event is acknowledged but not applied again, even if the
+	// payment would otherwise accept it
+	database.GetDB().Model(&models.Payment{}).Where("id = ?", payment.ID).Update("status", models.PaymentStatusPending)
+	if code := deliver("stripe", "Stripe-Signature", signStripe(payload, stripeWebhookSecret)); code != http.StatusOK {
+		t.Fatalf("replayed webhook: %d, want 200", code)
+	}
+	if got := status(); got != models.PaymentStatusPending || credits() != 4 {
+		t.Errorf("replayed webhook was applied: payment %s with %d credits", got, credits())
+	}
+
+	var events int64
+	database.GetDB().Model(&models.WebhookEvent{}).Where("id = ?", "evt_3PqB2dKx8ZfYqW1r0aB9cD1e").Count(&events)
+	if events != 1 {
+		t.Errorf("%d webhook events recorded, want 1", events)
+	}
+}
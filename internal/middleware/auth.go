@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/models"
 	"github.com/ukuvago/angel-platform/internal/services"
 )
@@ -35,10 +37,46 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		suspended, err := accountSuspended(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+		if suspended {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": services.ErrAccountSuspended.Error(),
+				"code":  "ACCOUNT_SUSPENDED",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("userID", claims.UserID)
 		c.Set("userEmail", claims.Email)
 		c.Set("userRole", claims.Role)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("twoFactor", claims.TwoFactor)
+
+		if claims.ImpersonatorID != nil {
+			c.Set("impersonatorID", *claims.ImpersonatorID)
+
+			// Everything done while impersonating is audited, including
+			// what was refused
+			defer func() {
+				authService.AuditImpersonatedRequest(claims, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP())
+			}()
+
+			if !claims.ImpersonationWrites && !readOnlyMethod(c.Request.Method) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "Impersonation is read-only",
+					"code":  "IMPERSONATION_READ_ONLY",
+				})
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
@@ -64,15 +102,32 @@ func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if suspended, err := accountSuspended(claims.UserID); err != nil || suspended {
+			c.Next()
+			return
+		}
 
 		c.Set("userID", claims.UserID)
 		c.Set("userEmail", claims.Email)
 		c.Set("userRole", claims.Role)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("twoFactor", claims.TwoFactor)
 
 		c.Next()
 	}
 }
 
+// accountSuspended reports whether the user has been suspended. It is read
+// from the database so a suspension applies to tokens already issued, and
+// fails for deleted users.
+func accountSuspended(userID uuid.UUID) (bool, error) {
+	var user models.User
+	if err := database.GetDB().Select("suspended_at").First(&user, "id = ?", userID).Error; err != nil {
+		return false, err
+	}
+	return user.IsSuspended(), nil
+}
+
 // RequireRole ensures the user has a specific role
 func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -96,19 +151,87 @@ func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	}
 }
 
-// RequireInvestor ensures the user is an investor
-func RequireInvestor() gin.HandlerFunc {
-	return RequireRole(models.RoleInvestor)
+// Authorize ensures the user's role may perform the action on at least some
+// resources of the kind. Handlers check the specific resource with authz.Can.
+func Authorize(action authz.Action, kind authz.Kind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := GetUserRole(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if !authz.Allowed(role, action, kind) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ForbidImpersonation refuses requests made with an impersonation token,
+// even one allowed to make changes. It guards signing, payments, offers and
+// the user's credentials.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := GetImpersonatorID(c); impersonating {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Not allowed while impersonating",
+				"code":  "IMPERSONATION_FORBIDDEN",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
 }
 
-// RequireDeveloper ensures the user is a developer
-func RequireDeveloper() gin.HandlerFunc {
-	return RequireRole(models.RoleDeveloper)
+// RequireTwoFactor ensures the user's session passed two-factor
+// authentication. When required is false every request passes.
+func RequireTwoFactor(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if required && !c.GetBool("twoFactor") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Two-factor authentication required",
+				"code":  "TWO_FACTOR_REQUIRED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
 }
 
-// RequireAdmin ensures the user is an admin
-func RequireAdmin() gin.HandlerFunc {
-	return RequireRole(models.RoleAdmin)
+// RequireVerifiedEmail ensures the user has verified their email address.
+// The flag is read from the database so a link followed mid-session counts.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		err := database.GetDB().Select("email_verified").First(&user, "id = ?", userID).Error
+		if err != nil || !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Email verification required",
+				"code":    "EMAIL_NOT_VERIFIED",
+				"message": "Please verify your email address before continuing",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
 }
 
 // GetUserID extracts user ID from context
@@ -120,6 +243,25 @@ func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	return userID.(uuid.UUID), true
 }
 
+// GetSessionID extracts the ID of the session the request's token belongs to
+func GetSessionID(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, exists := c.Get("sessionID")
+	if !exists {
+		return uuid.Nil, false
+	}
+	return sessionID.(uuid.UUID), true
+}
+
+// GetImpersonatorID returns the admin acting as the user, if the request
+// was made with an impersonation token
+func GetImpersonatorID(c *gin.Context) (uuid.UUID, bool) {
+	impersonatorID, exists := c.Get("impersonatorID")
+	if !exists {
+		return uuid.Nil, false
+	}
+	return impersonatorID.(uuid.UUID), true
+}
+
 // GetUserRole extracts user role from context
 func GetUserRole(c *gin.Context) (models.UserRole, bool) {
 	role, exists := c.Get("userRole")
@@ -128,3 +270,41 @@ func GetUserRole(c *gin.Context) (models.UserRole, bool) {
 	}
 	return role.(models.UserRole), true
 }
+
+// GetSubject returns the authenticated user as an authorization subject,
+// with the organisation they belong to
+func GetSubject(c *gin.Context) (authz.Subject, bool) {
+	userID, exists := GetUserID(c)
+	if !exists {
+		return authz.Subject{}, false
+	}
+	role, _ := GetUserRole(c)
+
+	sub := authz.Subject{ID: userID, Role: role}
+	if member := GetOrganizationMembership(c); member != nil {
+		sub.OrganizationID = member.OrganizationID
+		sub.OrganizationRole = member.Role
+	}
+	return sub, true
+}
+
+// GetOrganizationMembership returns the user's organisation membership, or
+// nil if they are not in one. It is looked up once per request.
+func GetOrganizationMembership(c *gin.Context) *models.OrganizationMember {
+	if member, exists := c.Get("organizationMember"); exists {
+		return member.(*models.OrganizationMember)
+	}
+
+	userID, exists := GetUserID(c)
+	if !exists {
+		return nil
+	}
+
+	member := services.OrganizationMembership(userID)
+	c.Set("organizationMember", member)
+	return member
+}
+
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+)
+
+// RequireVerifiedInvestor ensures an investor's identity and accreditation
+// have been approved. Other roles pass through.
+func RequireVerifiedInvestor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		role, _ := GetUserRole(c)
+		if role != models.RoleInvestor {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		err := database.GetDB().Select("kyc_status").First(&user, "id = ?", userID).Error
+		if err != nil || user.KYCStatus != models.KYCStatusApproved {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Investor verification required",
+				"code":    "KYC_REQUIRED",
+				"message": "Your identity and accreditation must be verified before you can invest",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
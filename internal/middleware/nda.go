@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/models"
 )
@@ -25,12 +26,7 @@ func RequireNDA() gin.HandlerFunc {
 			return
 		}
 
-		db := database.GetDB()
-		var nda models.NDA
-		err := db.Where("investor_id = ?", userID).
-			Order("signed_at DESC").
-			First(&nda).Error
-
+		nda, err := LatestNDA(c, userID)
 		if err != nil {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "NDA signature required",
@@ -66,12 +62,7 @@ func CheckNDAStatus() gin.HandlerFunc {
 			return
 		}
 
-		db := database.GetDB()
-		var nda models.NDA
-		err := db.Where("investor_id = ?", userID).
-			Order("signed_at DESC").
-			First(&nda).Error
-
+		nda, err := LatestNDA(c, userID)
 		if err != nil || !nda.IsValid() {
 			c.Set("hasNDA", false)
 		} else {
@@ -82,3 +73,18 @@ func CheckNDAStatus() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// LatestNDA returns the newest NDA covering the user: their own, or one
+// signed on behalf of their organisation
+func LatestNDA(c *gin.Context, userID uuid.UUID) (*models.NDA, error) {
+	query := database.GetDB().Preload("Organization").Where("investor_id = ?", userID)
+	if member := GetOrganizationMembership(c); member != nil {
+		query = query.Or("organization_id = ?", member.OrganizationID)
+	}
+
+	var nda models.NDA
+	if err := query.Order("signed_at DESC").First(&nda).Error; err != nil {
+		return nil, err
+	}
+	return &nda, nil
+}
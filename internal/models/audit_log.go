@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditAction string
+
+const (
+	AuditAccountLocked   AuditAction = "account_locked"
+	AuditAccountUnlocked AuditAction = "account_unlocked"
+	AuditKYCApproved     AuditAction = "kyc_approved"
+	AuditKYCRejected     AuditAction = "kyc_rejected"
+
+	AuditProjectTransferred AuditAction = "project_transferred"
+
+	AuditUserSuspended       AuditAction = "user_suspended"
+	AuditUserUnsuspended     AuditAction = "user_unsuspended"
+	AuditRoleChanged         AuditAction = "role_changed"
+	AuditPasswordResetForced AuditAction = "password_reset_forced"
+	AuditSessionsRevoked     AuditAction = "sessions_revoked"
+	AuditUserDeleted         AuditAction = "user_deleted"
+
+	AuditImpersonationStarted AuditAction = "impersonation_started"
+	AuditImpersonatedRequest  AuditAction = "impersonated_request"
+)
+
+// AuditLog records a security-relevant action. Entries are never updated or deleted.
+type AuditLog struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primary_key" json:"id"`
+	Action    AuditAction `gorm:"type:varchar(40);not null;index" json:"action"`
+	ActorID   *uuid.UUID  `gorm:"type:uuid;index" json:"actor_id,omitempty"` // Who acted; empty when the platform acted on its own
+	UserID    *uuid.UUID  `gorm:"type:uuid;index" json:"user_id,omitempty"`  // The account the action concerns
+	IPAddress string      `json:"ip_address,omitempty"`
+	Details   string      `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt time.Time   `gorm:"index" json:"created_at"`
+
+	// Relations
+	Actor *User `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	User  *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// LoginAttempt tracks failed attempts for one limiter key, such as an account
+// or an IP address, when attempts are limited through the database
+type LoginAttempt struct {
+	Key            string     `gorm:"primary_key" json:"key"`
+	Failures       int        `gorm:"not null;default:0" json:"failures"`
+	FirstFailureAt time.Time  `json:"first_failure_at"`
+	BlockedUntil   *time.Time `json:"blocked_until,omitempty"`
+}
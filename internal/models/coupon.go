@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CouponType string
+
+const (
+	CouponTypePercent CouponType = "percent" // Value is a percentage of the plan price
+	CouponTypeFixed   CouponType = "fixed"   // Value is an amount in cents off the plan price
+)
+
+// Coupon is an admin-managed promo code that discounts or waives the view fee
+type Coupon struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Code           string         `gorm:"uniqueIndex;not null" json:"code"` // Stored upper-case
+	Description    string         `json:"description"`
+	Type           CouponType     `gorm:"type:varchar(20);not null" json:"type"`
+	Value          int64          `gorm:"not null" json:"value"`
+	Currency       string         `json:"currency,omitempty"`               // Currency of fixed discounts
+	MaxRedemptions int            `gorm:"default:0" json:"max_redemptions"` // 0 means no limit
+	Redemptions    int            `gorm:"default:0" json:"redemptions"`     // Completed payments that used the coupon
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
+	PlanID         *uuid.UUID     `gorm:"type:uuid;index" json:"plan_id,omitempty"` // Only valid for this plan
+	UserID         *uuid.UUID     `gorm:"type:uuid;index" json:"user_id,omitempty"` // Only valid for this investor
+	Active         bool           `gorm:"not null" json:"active"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// NormalizeCouponCode returns the form coupon codes are stored and looked up in
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *Coupon) IsExpired() bool {
+	return c.ExpiresAt != nil && time.Now().After(*c.ExpiresAt)
+}
+
+func (c *Coupon) IsExhausted() bool {
+	return c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions
+}
+
+// Discount returns the amount in cents the coupon takes off a price. It never
+// exceeds the price itself.
+func (c *Coupon) Discount(amount int64) int64 {
+	var discount int64
+	switch c.Type {
+	case CouponTypePercent:
+		discount = amount * c.Value / 100
+	case CouponTypeFixed:
+		discount = c.Value
+	}
+	if discount > amount {
+		return amount
+	}
+	return discount
+}
+
+// CouponRedemption records a completed payment that used a coupon. Each
+// investor redeems a coupon at most once.
+type CouponRedemption struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CouponID   uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_coupon_redemptions_coupon_investor" json:"coupon_id"`
+	PaymentID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"payment_id"`
+	InvestorID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_coupon_redemptions_coupon_investor" json:"investor_id"`
+	Discount   int64     `gorm:"not null" json:"discount"` // Amount in cents
+	Currency   string    `gorm:"not null" json:"currency"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relations
+	Investor *User `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
+}
+
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
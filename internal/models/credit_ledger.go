@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CreditEntryType string
+
+const (
+	CreditEntryPurchase   CreditEntryType = "purchase"
+	CreditEntryConsume    CreditEntryType = "consume"
+	CreditEntryRefund     CreditEntryType = "refund"
+	CreditEntryAdminGrant CreditEntryType = "admin_grant"
+	CreditEntryExpiry     CreditEntryType = "expiry"
+)
+
+// ErrLedgerImmutable is returned when code attempts to change a recorded ledger entry
+var ErrLedgerImmutable = errors.New("credit ledger entries are append-only")
+
+// CreditLedgerEntry is an append-only record of a change to a payment's view credits.
+// The credit balance of a payment is the sum of the deltas of its entries.
+type CreditLedgerEntry struct {
+	ID         uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	InvestorID uuid.UUID       `gorm:"type:uuid;not null;index" json:"investor_id"`
+	PaymentID  uuid.UUID       `gorm:"type:uuid;not null;index" json:"payment_id"`
+	Type       CreditEntryType `gorm:"type:varchar(20);not null" json:"type"`
+	Delta      int             `gorm:"not null" json:"delta"`                 // Positive adds credits, negative removes them
+	ProjectID  *uuid.UUID      `gorm:"type:uuid" json:"project_id,omitempty"` // Set on consume entries
+	ActorID    *uuid.UUID      `gorm:"type:uuid" json:"actor_id,omitempty"`   // Admin who granted or refunded
+	Reason     string          `json:"reason,omitempty"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+
+	// Relations
+	Project *Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+}
+
+func (e *CreditLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *CreditLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+func (e *CreditLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
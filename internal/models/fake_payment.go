@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// FakeIntent is an intent held by the fake payment provider. It is stored
+// with the rest of the data so demo payments survive restarts and can be
+// confirmed on any instance.
+type FakeIntent struct {
+	ID         string    `gorm:"primary_key" json:"id"` // fake_pi_...
+	Status     string    `gorm:"type:varchar(20);not null" json:"status"`
+	Amount     int64     `gorm:"not null" json:"amount"` // Amount in cents
+	Currency   string    `gorm:"not null" json:"currency"`
+	Metadata   string    `gorm:"type:text" json:"metadata,omitempty"` // JSON object
+	ReceiptURL string    `json:"receipt_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FakeRefund is money the fake payment provider has paid back on an intent
+type FakeRefund struct {
+	ID             string    `gorm:"primary_key" json:"id"` // fake_re_...
+	IntentID       string    `gorm:"not null;index" json:"intent_id"`
+	Amount         int64     `gorm:"not null" json:"amount"`             // Amount in cents
+	IdempotencyKey *string   `gorm:"uniqueIndex" json:"idempotency_key"` // Nil when the caller sent none
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -18,19 +18,20 @@ const (
 )
 
 type InvestmentOffer struct {
-	ID            uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	InvestorID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"investor_id"`
-	ProjectID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"project_id"`
-	OfferAmount   float64        `gorm:"not null" json:"offer_amount"`
-	EquityRequest float64        `json:"equity_request"` // Percentage if applicable
-	TermsNotes    string         `gorm:"type:text" json:"terms_notes"`
-	Status        OfferStatus    `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	ResponseNotes string         `gorm:"type:text" json:"response_notes,omitempty"`
-	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	RespondedAt   *time.Time     `json:"responded_at,omitempty"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	InvestorID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"investor_id"`
+	OrganizationID *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"` // Set when made on behalf of an organisation
+	ProjectID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"project_id"`
+	OfferAmount    float64        `gorm:"not null" json:"offer_amount"`
+	EquityRequest  float64        `json:"equity_request"` // Percentage if applicable
+	TermsNotes     string         `gorm:"type:text" json:"terms_notes"`
+	Status         OfferStatus    `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	ResponseNotes  string         `gorm:"type:text" json:"response_notes,omitempty"`
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	RespondedAt    *time.Time     `json:"responded_at,omitempty"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
 	Investor  *User      `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
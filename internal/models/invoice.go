@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invoice is the tax invoice for a paid view fee. Billing details are copied
+// from the investor when the invoice is issued so later profile edits do not
+// change an invoice that has already been sent.
+type Invoice struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Number      int64     `gorm:"not null;uniqueIndex" json:"number"` // Sequential, without gaps
+	PaymentID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"payment_id"`
+	InvestorID  uuid.UUID `gorm:"type:uuid;not null;index" json:"investor_id"`
+	BilledName  string    `gorm:"not null" json:"billed_name"`
+	BilledEmail string    `gorm:"not null" json:"billed_email"`
+	CompanyName string    `json:"company_name"`
+	Description string    `json:"description"`
+	Currency    string    `gorm:"not null" json:"currency"`
+	Subtotal    int64     `gorm:"not null" json:"subtotal"`   // Amount excluding VAT, in cents
+	VATRate     float64   `gorm:"not null" json:"vat_rate"`   // Percentage
+	VATAmount   int64     `gorm:"not null" json:"vat_amount"` // In cents
+	Total       int64     `gorm:"not null" json:"total"`      // Amount paid, in cents
+	IssuedAt    time.Time `gorm:"not null;index" json:"issued_at"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Relations
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+func (i *Invoice) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// InvoiceNumber returns the number printed on the invoice, e.g. INV-000042
+func (i *Invoice) InvoiceNumber() string {
+	return fmt.Sprintf("INV-%06d", i.Number)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KYCStatus is where an investor stands in identity and accreditation checks
+type KYCStatus string
+
+const (
+	KYCStatusNone     KYCStatus = "none"
+	KYCStatusPending  KYCStatus = "pending"
+	KYCStatusApproved KYCStatus = "approved"
+	KYCStatusRejected KYCStatus = "rejected"
+)
+
+type KYCDocumentType string
+
+const (
+	KYCDocumentIdentity      KYCDocumentType = "identity"      // Passport, ID card or driver's licence
+	KYCDocumentAccreditation KYCDocumentType = "accreditation" // Proof the investor is accredited
+)
+
+// KYCSubmission is a set of documents an investor sent for review. An
+// investor may resubmit after a rejection; earlier submissions are kept.
+type KYCSubmission struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	InvestorID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"investor_id"`
+	Status          KYCStatus  `gorm:"type:varchar(20);not null;index" json:"status"`
+	RejectionReason string     `gorm:"type:text" json:"rejection_reason,omitempty"`
+	ReviewedByID    *uuid.UUID `gorm:"type:uuid" json:"reviewed_by_id,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relations
+	Investor  *User         `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
+	Documents []KYCDocument `gorm:"foreignKey:SubmissionID" json:"documents,omitempty"`
+}
+
+func (k *KYCSubmission) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// KYCDocument is an uploaded file of a submission. Files are kept outside the
+// public upload directory and only served to the investor and admins.
+type KYCDocument struct {
+	ID           uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	SubmissionID uuid.UUID       `gorm:"type:uuid;not null;index" json:"submission_id"`
+	Type         KYCDocumentType `gorm:"type:varchar(20);not null" json:"type"`
+	FilePath     string          `gorm:"not null" json:"-"`
+	FileName     string          `json:"file_name"`
+	CreatedAt    time.Time       `json:"created_at"`
+
+	// Relations
+	Submission *KYCSubmission `gorm:"foreignKey:SubmissionID" json:"-"`
+}
+
+func (d *KYCDocument) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -8,21 +8,23 @@ import (
 )
 
 type NDA struct {
-	ID            uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	InvestorID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"investor_id"`
-	SignatureData string         `gorm:"type:text;not null" json:"signature_data"` // Base64 encoded signature image
-	SignedName    string         `gorm:"not null" json:"signed_name"`
-	IPAddress     string         `gorm:"not null" json:"ip_address"`
-	UserAgent     string         `json:"user_agent"`
-	SignedAt      time.Time      `gorm:"not null" json:"signed_at"`
-	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
-	Version       string         `gorm:"default:'1.0'" json:"version"`
-	DocumentHash  string         `json:"document_hash"` // Hash of NDA content at time of signing
-	CreatedAt     time.Time      `json:"created_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	InvestorID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"investor_id"`
+	OrganizationID *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"` // Set when signed on behalf of an organisation
+	SignatureData  string         `gorm:"type:text;not null" json:"signature_data"`         // Base64 encoded signature image
+	SignedName     string         `gorm:"not null" json:"signed_name"`
+	IPAddress      string         `gorm:"not null" json:"ip_address"`
+	UserAgent      string         `json:"user_agent"`
+	SignedAt       time.Time      `gorm:"not null" json:"signed_at"`
+	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
+	Version        string         `gorm:"default:'1.0'" json:"version"`
+	DocumentHash   string         `json:"document_hash"` // Hash of NDA content at time of signing
+	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Investor *User `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
+	Investor     *User         `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 }
 
 func (n *NDA) BeforeCreate(tx *gorm.DB) error {
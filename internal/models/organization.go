@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrganizationType string
+
+const (
+	OrganizationTypeInvestmentFirm OrganizationType = "investment_firm" // Angel funds and syndicates; members are investors
+	OrganizationTypeStartup        OrganizationType = "startup"         // Companies raising money; members are developers
+)
+
+// OrganizationTypeFor returns the kind of organisation users of a role can
+// form or join, or "" if they cannot belong to one
+func OrganizationTypeFor(role UserRole) OrganizationType {
+	switch role {
+	case RoleInvestor:
+		return OrganizationTypeInvestmentFirm
+	case RoleDeveloper:
+		return OrganizationTypeStartup
+	default:
+		return ""
+	}
+}
+
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "owner"  // Manages members and invitations, and acts for the organisation
+	OrganizationRoleMember OrganizationRole = "member" // Acts for the organisation: signs NDAs, pays, makes offers, signs term sheets
+	OrganizationRoleViewer OrganizationRole = "viewer" // Only sees what belongs to the organisation
+)
+
+// CanAct reports whether the role may act on the organisation's behalf
+func (r OrganizationRole) CanAct() bool {
+	return r == OrganizationRoleOwner || r == OrganizationRoleMember
+}
+
+// Organization groups users who invest or raise money together. NDAs,
+// payments, offers and projects made by its owners and members belong to it
+// and are shared by everyone in it.
+type Organization struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string           `gorm:"not null" json:"name"`
+	Type      OrganizationType `gorm:"type:varchar(20);not null" json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	DeletedAt gorm.DeletedAt   `gorm:"index" json:"-"`
+
+	// Relations
+	Members     []OrganizationMember     `gorm:"foreignKey:OrganizationID" json:"members,omitempty"`
+	Invitations []OrganizationInvitation `gorm:"foreignKey:OrganizationID" json:"invitations,omitempty"`
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// OrganizationMember places a user in an organisation. A user belongs to at
+// most one organisation.
+type OrganizationMember struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	OrganizationID uuid.UUID        `gorm:"type:uuid;not null;index" json:"organization_id"`
+	UserID         uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	Role           OrganizationRole `gorm:"type:varchar(20);not null" json:"role"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (m *OrganizationMember) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// OrganizationInvitation is an emailed invitation to join an organisation.
+// Only the SHA-256 hash of its token is stored.
+type OrganizationInvitation struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	OrganizationID uuid.UUID        `gorm:"type:uuid;not null;index" json:"organization_id"`
+	Email          string           `gorm:"not null;index" json:"email"`
+	Role           OrganizationRole `gorm:"type:varchar(20);not null" json:"role"`
+	TokenHash      string           `gorm:"not null;uniqueIndex" json:"-"`
+	InvitedByID    uuid.UUID        `gorm:"type:uuid;not null" json:"invited_by_id"`
+	ExpiresAt      time.Time        `gorm:"not null" json:"expires_at"`
+	AcceptedAt     *time.Time       `json:"accepted_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	InvitedBy    *User         `gorm:"foreignKey:InvitedByID" json:"invited_by,omitempty"`
+}
+
+func (i *OrganizationInvitation) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
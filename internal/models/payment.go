@@ -11,32 +11,48 @@ import (
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusCompleted PaymentStatus = "completed"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusRefunded  PaymentStatus = "refunded"
+	PaymentStatusPending       PaymentStatus = "pending"
+	PaymentStatusCompleted     PaymentStatus = "completed"
+	PaymentStatusFailed        PaymentStatus = "failed"
+	PaymentStatusRefundPending PaymentStatus = "refund_pending" // Credits voided; the provider has not yet confirmed the refund
+	PaymentStatusRefunded      PaymentStatus = "refunded"
 )
 
 type Payment struct {
 	ID                uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
 	InvestorID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"investor_id"`
+	OrganizationID    *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"` // Set when the credits are shared with an organisation
 	Amount            int64          `gorm:"not null" json:"amount"` // Amount in cents
 	Currency          string         `gorm:"not null;default:'usd'" json:"currency"`
 	StripePaymentID   string         `gorm:"index" json:"stripe_payment_id,omitempty"`
 	StripeClientSecret string        `json:"-"`
+	Provider          string         `gorm:"type:varchar(20)" json:"provider,omitempty"` // Empty for payments made before providers were recorded
+	CheckoutURL       string         `json:"checkout_url,omitempty"`                      // Hosted checkout page, for redirect-based providers
 	Status            PaymentStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	ProjectsRemaining int            `gorm:"not null" json:"projects_remaining"`
+	ProjectsRemaining int            `gorm:"-" json:"projects_remaining"` // Computed from the credit ledger
 	ProjectsTotal     int            `gorm:"not null" json:"projects_total"`
+	PlanID            *uuid.UUID     `gorm:"type:uuid;index" json:"plan_id,omitempty"`
+	CouponID          *uuid.UUID     `gorm:"type:uuid;index" json:"coupon_id,omitempty"`
+	DiscountAmount    int64          `gorm:"default:0" json:"discount_amount"` // Taken off the plan price by the coupon, in cents
+	Unlimited         bool           `gorm:"default:false" json:"unlimited"`
+	ExpiresAt         *time.Time     `json:"expires_at,omitempty"` // Credits lapse after this time
+	ReminderSentAt    *time.Time     `json:"-"`                    // When the investor was warned of the coming expiry
 	Description       string         `json:"description"`
 	ReceiptURL        string         `json:"receipt_url,omitempty"`
+	RefundedAmount    int64          `gorm:"default:0" json:"refunded_amount"` // Amount in cents; while refund_pending, the amount being refunded
+	RefundID          string         `json:"refund_id,omitempty"`
+	RefundedAt        *time.Time     `json:"refunded_at,omitempty"`
+	RefundAttemptedAt *time.Time     `json:"-"` // Set while a call to refund at the provider is in flight
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	CompletedAt       *time.Time     `json:"completed_at,omitempty"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Investor *User         `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
-	Views    []ProjectView `gorm:"foreignKey:PaymentID" json:"views,omitempty"`
+	Investor *User               `gorm:"foreignKey:InvestorID" json:"investor,omitempty"`
+	Plan     *PricingPlan        `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
+	Views    []ProjectView       `gorm:"foreignKey:PaymentID" json:"views,omitempty"`
+	Credits  []CreditLedgerEntry `gorm:"foreignKey:PaymentID" json:"credits,omitempty"`
 }
 
 func (p *Payment) BeforeCreate(tx *gorm.DB) error {
@@ -47,15 +63,19 @@ func (p *Payment) BeforeCreate(tx *gorm.DB) error {
 }
 
 func (p *Payment) CanViewMore() bool {
-	return p.Status == PaymentStatusCompleted && p.ProjectsRemaining > 0
+	return p.Status == PaymentStatusCompleted && !p.IsExpired() && (p.Unlimited || p.ProjectsRemaining > 0)
 }
 
-func (p *Payment) UseCredit() bool {
-	if p.ProjectsRemaining > 0 {
-		p.ProjectsRemaining--
-		return true
+// NetAmount is the amount kept after refunds
+func (p *Payment) NetAmount() int64 {
+	return p.Amount - p.RefundedAmount
+}
+
+func (p *Payment) IsExpired() bool {
+	if p.ExpiresAt == nil {
+		return false
 	}
-	return false
+	return time.Now().After(*p.ExpiresAt)
 }
 
 // PaymentResponse is the safe representation for API responses
@@ -65,30 +85,47 @@ type PaymentResponse struct {
 	AmountFormatted   string        `json:"amount_formatted"`
 	Currency          string        `json:"currency"`
 	Status            PaymentStatus `json:"status"`
+	Provider          string        `json:"provider,omitempty"`
 	ProjectsRemaining int           `json:"projects_remaining"`
 	ProjectsTotal     int           `json:"projects_total"`
+	PlanID            *uuid.UUID    `json:"plan_id,omitempty"`
+	Unlimited         bool          `json:"unlimited"`
+	DiscountAmount    int64         `json:"discount_amount"`
+	Description       string        `json:"description"`
 	ReceiptURL        string        `json:"receipt_url,omitempty"`
+	RefundedAmount    int64         `json:"refunded_amount"`
 	CreatedAt         time.Time     `json:"created_at"`
 	CompletedAt       *time.Time    `json:"completed_at,omitempty"`
+	ExpiresAt         *time.Time    `json:"expires_at,omitempty"`
+	RefundedAt        *time.Time    `json:"refunded_at,omitempty"`
 }
 
 func (p *Payment) ToResponse() PaymentResponse {
-	formatted := formatCurrency(p.Amount, p.Currency)
+	formatted := FormatCurrency(p.Amount, p.Currency)
 	return PaymentResponse{
 		ID:                p.ID,
 		Amount:            p.Amount,
 		AmountFormatted:   formatted,
 		Currency:          p.Currency,
 		Status:            p.Status,
+		Provider:          p.Provider,
 		ProjectsRemaining: p.ProjectsRemaining,
 		ProjectsTotal:     p.ProjectsTotal,
+		PlanID:            p.PlanID,
+		Unlimited:         p.Unlimited,
+		DiscountAmount:    p.DiscountAmount,
+		Description:       p.Description,
 		ReceiptURL:        p.ReceiptURL,
+		RefundedAmount:    p.RefundedAmount,
 		CreatedAt:         p.CreatedAt,
 		CompletedAt:       p.CompletedAt,
+		ExpiresAt:         p.ExpiresAt,
+		RefundedAt:        p.RefundedAt,
 	}
 }
 
-func formatCurrency(amount int64, currency string) string {
+// FormatCurrency formats an amount in cents with the currency's symbol
+func FormatCurrency(amount int64, currency string) string {
 	major := float64(amount) / 100
 	formatted := fmt.Sprintf("%.2f", major)
 	switch currency {
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PricingPlan is an admin-managed package of project views investors can buy
+type PricingPlan struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Name         string         `gorm:"not null" json:"name"`
+	Description  string         `json:"description"`
+	Amount       int64          `gorm:"not null" json:"amount"` // Amount in cents
+	Currency     string         `gorm:"not null;default:'usd'" json:"currency"`
+	Credits      int            `gorm:"not null;default:0" json:"credits"` // Project views included, ignored when Unlimited
+	Unlimited    bool           `gorm:"default:false" json:"unlimited"`
+	ValidityDays int            `gorm:"default:0" json:"validity_days"` // 0 means credits never expire
+	Active       bool           `gorm:"not null" json:"active"`
+	SortOrder    int            `gorm:"default:0" json:"sort_order"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (p *PricingPlan) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Summary describes what the plan grants, e.g. for payment descriptions
+func (p *PricingPlan) Summary() string {
+	var summary string
+	if p.Unlimited {
+		summary = "unlimited project views"
+	} else if p.Credits == 1 {
+		summary = "access to view 1 project"
+	} else {
+		summary = fmt.Sprintf("access to view up to %d projects", p.Credits)
+	}
+
+	if p.ValidityDays > 0 {
+		summary += fmt.Sprintf(" for %d days", p.ValidityDays)
+	}
+	return summary
+}
+
+// PricingPlanResponse is the representation shown to investors
+type PricingPlanResponse struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Summary         string    `json:"summary"`
+	Amount          int64     `json:"amount"`
+	AmountFormatted string    `json:"amount_formatted"`
+	Currency        string    `json:"currency"`
+	Credits         int       `json:"credits"`
+	Unlimited       bool      `json:"unlimited"`
+	ValidityDays    int       `json:"validity_days"`
+}
+
+func (p *PricingPlan) ToResponse() PricingPlanResponse {
+	return PricingPlanResponse{
+		ID:              p.ID,
+		Name:            p.Name,
+		Description:     p.Description,
+		Summary:         p.Summary(),
+		Amount:          p.Amount,
+		AmountFormatted: FormatCurrency(p.Amount, p.Currency),
+		Currency:        p.Currency,
+		Credits:         p.Credits,
+		Unlimited:       p.Unlimited,
+		ValidityDays:    p.ValidityDays,
+	}
+}
@@ -63,6 +63,7 @@ type Project struct {
 	RejectionReason string         `gorm:"type:text" json:"rejection_reason,omitempty"`
 	ApprovedAt      *time.Time     `json:"approved_at,omitempty"`
 	ApprovedBy      *uuid.UUID     `gorm:"type:uuid" json:"approved_by,omitempty"`
+	OrganizationID  *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"` // The startup the project belongs to, if any
 	ViewCount       int            `gorm:"default:0" json:"view_count"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
@@ -75,6 +76,9 @@ type Project struct {
 	TeamMembers []TeamMember      `gorm:"foreignKey:ProjectID" json:"team_members,omitempty"`
 	Views       []ProjectView     `gorm:"foreignKey:ProjectID" json:"views,omitempty"`
 	Offers      []InvestmentOffer `gorm:"foreignKey:ProjectID" json:"offers,omitempty"`
+
+	// Loaded for access checks; listed through the collaborators endpoint
+	Collaborators []ProjectCollaborator `gorm:"foreignKey:ProjectID" json:"-"`
 }
 
 func (p *Project) BeforeCreate(tx *gorm.DB) error {
@@ -138,10 +142,12 @@ func (pi *ProjectImage) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// ProjectView records that an investor spent a credit on a project.
+// An investor can only hold one view per project.
 type ProjectView struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	InvestorID uuid.UUID `gorm:"type:uuid;not null;index" json:"investor_id"`
-	ProjectID  uuid.UUID `gorm:"type:uuid;not null;index" json:"project_id"`
+	InvestorID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_project_views_investor_project" json:"investor_id"`
+	ProjectID  uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_project_views_investor_project" json:"project_id"`
 	PaymentID  uuid.UUID `gorm:"type:uuid;not null;index" json:"payment_id"`
 	ViewedAt   time.Time `gorm:"not null" json:"viewed_at"`
 
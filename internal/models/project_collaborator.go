@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ProjectPermission string
+
+const (
+	ProjectPermissionOwner  ProjectPermission = "owner"  // The project's developer; manages collaborators and may hand the project over
+	ProjectPermissionEditor ProjectPermission = "editor" // Edits and submits the project, responds to its offers and signs its term sheets
+	ProjectPermissionViewer ProjectPermission = "viewer" // Only sees the project and its offers and term sheets
+)
+
+// ProjectCollaborator gives a developer a permission on a project. Every
+// project has exactly one owner, who is also its DeveloperID.
+type ProjectCollaborator struct {
+	ID         uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	ProjectID  uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_project_collaborators_project_user" json:"project_id"`
+	UserID     uuid.UUID         `gorm:"type:uuid;not null;index;uniqueIndex:idx_project_collaborators_project_user" json:"user_id"`
+	Permission ProjectPermission `gorm:"type:varchar(20);not null" json:"permission"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+
+	// Relations
+	Project *Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (pc *ProjectCollaborator) BeforeCreate(tx *gorm.DB) error {
+	if pc.ID == uuid.Nil {
+		pc.ID = uuid.New()
+	}
+	return nil
+}
+
+// ProjectInvitation is an emailed invitation to collaborate on a project.
+// Only the SHA-256 hash of its token is stored.
+type ProjectInvitation struct {
+	ID          uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	ProjectID   uuid.UUID         `gorm:"type:uuid;not null;index" json:"project_id"`
+	Email       string            `gorm:"not null;index" json:"email"`
+	Permission  ProjectPermission `gorm:"type:varchar(20);not null" json:"permission"`
+	TokenHash   string            `gorm:"not null;uniqueIndex" json:"-"`
+	InvitedByID uuid.UUID         `gorm:"type:uuid;not null" json:"invited_by_id"`
+	ExpiresAt   time.Time         `gorm:"not null" json:"expires_at"`
+	AcceptedAt  *time.Time        `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+
+	// Relations
+	Project   *Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	InvitedBy *User    `gorm:"foreignKey:InvitedByID" json:"invited_by,omitempty"`
+}
+
+func (pi *ProjectInvitation) BeforeCreate(tx *gorm.DB) error {
+	if pi.ID == uuid.Nil {
+		pi.ID = uuid.New()
+	}
+	return nil
+}
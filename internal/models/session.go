@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a signed-in device. Access tokens carry the session ID and stop
+// working as soon as the session is revoked; the refresh token that renews
+// them is stored only as a hash and replaced on every use.
+type Session struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"not null;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"index" json:"-"` // Hash of the token the current one replaced, to detect reuse
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	TwoFactor         bool       `gorm:"default:false" json:"two_factor"` // Signed in with a second factor, or enabled 2FA during the session
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	ImpersonatorID    *uuid.UUID `gorm:"type:uuid;index" json:"impersonator_id,omitempty"` // Admin the session was started for, when it impersonates the user
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive reports whether the session has been neither revoked nor left to expire
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
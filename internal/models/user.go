@@ -26,9 +26,12 @@ type User struct {
 	CompanyName   string         `json:"company_name"`
 	Bio           string         `gorm:"type:text" json:"bio"`
 	EmailVerified bool           `gorm:"default:false" json:"email_verified"`
-	VerifyToken   string         `gorm:"index" json:"-"`
-	ResetToken    string         `gorm:"index" json:"-"`
-	ResetExpires  *time.Time     `json:"-"`
+	TOTPSecret    string         `json:"-"` // Base32 secret, set at enrolment before 2FA is enabled
+	TOTPEnabled   bool           `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep  int64          `json:"-"` // Time step of the last accepted code, so no code works twice
+	KYCStatus     KYCStatus      `gorm:"type:varchar(20);default:'none'" json:"kyc_status"`
+	SuspendedAt   *time.Time     `json:"suspended_at,omitempty"` // Set while an admin has suspended the account
+	SuspendReason string         `json:"suspend_reason,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -51,6 +54,11 @@ func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+// IsSuspended reports whether an admin has suspended the account
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
 // UserResponse is a safe representation without sensitive fields
 type UserResponse struct {
 	ID            uuid.UUID `json:"id"`
@@ -62,6 +70,10 @@ type UserResponse struct {
 	CompanyName   string    `json:"company_name"`
 	Bio           string    `json:"bio"`
 	EmailVerified bool      `json:"email_verified"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	KYCStatus     KYCStatus `json:"kyc_status"`
+	Suspended     bool      `json:"suspended"`
+	SuspendReason string    `json:"suspend_reason,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
@@ -76,6 +88,10 @@ func (u *User) ToResponse() UserResponse {
 		CompanyName:   u.CompanyName,
 		Bio:           u.Bio,
 		EmailVerified: u.EmailVerified,
+		TOTPEnabled:   u.TOTPEnabled,
+		KYCStatus:     u.KYCStatus,
+		Suspended:     u.IsSuspended(),
+		SuspendReason: u.SuspendReason,
 		CreatedAt:     u.CreatedAt,
 	}
 }
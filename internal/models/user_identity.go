@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to their account at an OpenID Connect provider
+type UserIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Provider    string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_identity_subject" json:"provider"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_identity_subject" json:"-"` // The provider's stable user ID ("sub" claim)
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// OIDCLogin is a sign-in started at an identity provider and not yet
+// completed. It is looked up by the hash of the state parameter and deleted
+// when the provider redirects back.
+type OIDCLogin struct {
+	StateHash    string    `gorm:"primaryKey" json:"-"`
+	Provider     string    `gorm:"type:varchar(50);not null" json:"provider"`
+	Nonce        string    `gorm:"not null" json:"-"`
+	CodeVerifier string    `gorm:"not null" json:"-"`            // PKCE verifier, sent with the code exchange
+	Role         UserRole  `gorm:"type:varchar(20)" json:"role"` // Role for a new account, empty when only signing in
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPurposeVerifyEmail   TokenPurpose = "verify_email"
+	TokenPurposePasswordReset TokenPurpose = "password_reset"
+	TokenPurposeMagicLink     TokenPurpose = "magic_link"
+)
+
+// UserToken is a single-use token emailed to a user, such as a verification
+// or password reset link. Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"type:varchar(30);not null;index" json:"purpose"`
+	TokenHash string       `gorm:"not null;uniqueIndex" json:"-"`
+	IPAddress string       `json:"ip_address"` // Where the token was requested from, if by the user
+	ExpiresAt time.Time    `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func (t *UserToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// HashToken returns the SHA-256 of a random token, such as a session or
+// emailed token, which is all the database keeps of it
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
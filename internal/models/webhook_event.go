@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEvent records a payment provider event that has already been handled,
+// so redelivered events are acknowledged without being applied twice
+type WebhookEvent struct {
+	ID          string    `gorm:"primary_key" json:"id"` // Provider event ID, e.g. evt_...
+	Provider    string    `gorm:"not null;default:'stripe'" json:"provider"`
+	Type        string    `gorm:"not null" json:"type"`
+	ProcessedAt time.Time `gorm:"not null" json:"processed_at"`
+}
+
+func (w *WebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if w.ProcessedAt.IsZero() {
+		w.ProcessedAt = time.Now()
+	}
+	return nil
+}
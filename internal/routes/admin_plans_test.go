@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestPricingPlanUpdates checks plans are stored in lowercase currencies and
+// that the last plan on sale cannot be taken off sale
+func TestPricingPlanUpdates(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+	token := testutil.Token(t, cfg, testutil.User(t, models.RoleAdmin))
+
+	var standard models.PricingPlan
+	if err := database.GetDB().First(&standard, "active = ?", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	plan := func(active bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":     "Rand",
+			"amount":   50000,
+			"currency": "ZAR",
+			"credits":  4,
+			"active":   active,
+		}
+	}
+
+	if status, body := testutil.Do(t, router, http.MethodPut, "/api/admin/plans/"+standard.ID.String(), token, plan(false)); status != http.StatusBadRequest {
+		t.Errorf("deactivate the only plan: %d %v", status, body)
+	}
+
+	status, body := testutil.Do(t, router, http.MethodPost, "/api/admin/plans", token, plan(true))
+	created, _ := body["plan"].(map[string]interface{})
+	if status != http.StatusCreated || created["currency"] != "zar" {
+		t.Fatalf("create plan: %d %v", status, body)
+	}
+
+	if status, body := testutil.Do(t, router, http.MethodPut, "/api/admin/plans/"+standard.ID.String(), token, plan(false)); status != http.StatusOK {
+		t.Fatalf("deactivate with another plan on sale: %d %v", status, body)
+	}
+	var stored models.PricingPlan
+	database.GetDB().First(&stored, "id = ?", standard.ID)
+	if stored.Active || stored.Currency != "zar" {
+		t.Errorf("updated plan: active %v, currency %q", stored.Active, stored.Currency)
+	}
+
+	id, _ := created["id"].(string)
+	if status, body := testutil.Do(t, router, http.MethodPut, "/api/admin/plans/"+id, token, plan(false)); status != http.StatusBadRequest {
+		t.Errorf("deactivate the last plan on sale: %d %v", status, body)
+	}
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestRevenueWithPendingRefunds checks the dashboard and the payment list
+// agree on revenue and refunds while a refund is pending
+func TestRevenueWithPendingRefunds(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+	token := testutil.Token(t, cfg, testutil.User(t, models.RoleAdmin))
+	investor := testutil.User(t, models.RoleInvestor)
+
+	now := time.Now()
+	for _, p := range []models.Payment{
+		{Status: models.PaymentStatusCompleted},
+		{Status: models.PaymentStatusRefundPending, RefundedAmount: 50000},
+		{Status: models.PaymentStatusRefunded, RefundedAmount: 20000, RefundedAt: &now},
+	} {
+		p.InvestorID = investor.ID
+		p.Amount = 50000
+		p.Currency = "usd"
+		p.ProjectsTotal = 4
+		p.CompletedAt = &now
+		if err := database.GetDB().Create(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, stats := testutil.Do(t, router, http.MethodGet, "/api/admin/stats", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("stats: %d %v", status, stats)
+	}
+	if stats["total_revenue"] != float64(80000) || stats["total_refunds"] != float64(70000) {
+		t.Errorf("dashboard: revenue %v, refunds %v; want 80000, 70000", stats["total_revenue"], stats["total_refunds"])
+	}
+
+	status, list := testutil.Do(t, router, http.MethodGet, "/api/admin/payments", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("payments: %d %v", status, list)
+	}
+	if list["net_revenue"] != stats["total_revenue"] || list["refunded"] != stats["total_refunds"] || list["gross_revenue"] != float64(150000) {
+		t.Errorf("payment list: gross %v, refunded %v, net %v", list["gross_revenue"], list["refunded"], list["net_revenue"])
+	}
+}
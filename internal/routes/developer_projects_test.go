@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestDeveloperProjects drives the developer project API as each kind of
+// caller, against a draft owned by one developer
+func TestDeveloperProjects(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+
+	var category models.Category
+	if err := database.GetDB().First(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+	owner := testutil.User(t, models.RoleDeveloper)
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		list   int
+		listed bool // Whether the owner's draft is in the caller's list
+		create int
+		update int
+		submit int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized, false, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"investor", testutil.User(t, models.RoleInvestor), http.StatusForbidden, false, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden},
+		{"owner", owner, http.StatusOK, true, http.StatusCreated, http.StatusOK, http.StatusOK},
+		{"other developer", testutil.User(t, models.RoleDeveloper), http.StatusOK, false, http.StatusCreated, http.StatusNotFound, http.StatusNotFound},
+		{"admin", testutil.User(t, models.RoleAdmin), http.StatusOK, false, http.StatusCreated, http.StatusOK, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := ""
+			if tt.user != nil {
+				token = testutil.Token(t, cfg, tt.user)
+			}
+			draft := testutil.Project(t, owner, models.ProjectStatusDraft)
+			path := "/api/developer/projects"
+
+			status, body := testutil.Do(t, router, http.MethodGet, path, token, nil)
+			if status != tt.list {
+				t.Errorf("list: %d, want %d: %v", status, tt.list, body)
+			}
+			listed := false
+			projects, _ := body["projects"].([]interface{})
+			for _, p := range projects {
+				project, _ := p.(map[string]interface{})
+				listed = listed || project["id"] == draft.ID.String()
+			}
+			if listed != tt.listed {
+				t.Errorf("list shows the owner's draft = %v, want %v", listed, tt.listed)
+			}
+
+			project := map[string]interface{}{
+				"title":          "Solar Kiosks",
+				"category_id":    category.ID,
+				"description":    "Pay-as-you-go solar charging for rural traders",
+				"contact_email":  "founders@example.com",
+				"contact_phone":  "+27 21 555 0100",
+				"min_investment": 10000,
+			}
+			if status, body := testutil.Do(t, router, http.MethodPost, path, token, project); status != tt.create {
+				t.Errorf("create: %d, want %d: %v", status, tt.create, body)
+			}
+
+			project["title"] = "Solar Kiosks 2.0"
+			if status, body := testutil.Do(t, router, http.MethodPut, path+"/"+draft.ID.String(), token, project); status != tt.update {
+				t.Errorf("update: %d, want %d: %v", status, tt.update, body)
+			}
+			if status, body := testutil.Do(t, router, http.MethodPost, path+"/"+draft.ID.String()+"/submit", token, nil); status != tt.submit {
+				t.Errorf("submit: %d, want %d: %v", status, tt.submit, body)
+			}
+
+			var stored models.Project
+			database.GetDB().First(&stored, "id = ?", draft.ID)
+			changed := stored.Title == "Solar Kiosks 2.0" && stored.Status == models.ProjectStatusPending
+			if changed != (tt.update == http.StatusOK) {
+				t.Errorf("draft is now %q, %s", stored.Title, stored.Status)
+			}
+		})
+	}
+}
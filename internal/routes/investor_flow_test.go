@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestInvestorFlow walks an investor from signing the NDA to making an offer,
+// paying through the fake provider. The payment is started and confirmed on
+// separate routers, as if by two instances of the server.
+func TestInvestorFlow(t *testing.T) {
+	cfg := testutil.Database(t)
+	first, second := SetupRouter(cfg), SetupRouter(cfg)
+
+	investor := testutil.User(t, models.RoleInvestor)
+	if err := database.GetDB().Model(investor).Update("kyc_status", models.KYCStatusApproved).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := testutil.Token(t, cfg, investor)
+	project := testutil.Project(t, testutil.User(t, models.RoleDeveloper), models.ProjectStatusApproved)
+	projectPath := "/api/projects/" + project.ID.String()
+
+	// Nothing but the NDA is open to a new investor
+	if status, body := testutil.Do(t, first, http.MethodPost, "/api/payments/create-intent", token, nil); body["code"] != "NDA_REQUIRED" {
+		t.Fatalf("paying before the NDA: %d %v", status, body)
+	}
+
+	status, body := testutil.Do(t, first, http.MethodPost, "/api/nda/sign", token, map[string]interface{}{
+		"signature_data": "c2lnbmF0dXJl",
+		"signed_name":    investor.FirstName + " " + investor.LastName,
+		"agreed":         true,
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("sign NDA: %d %v", status, body)
+	}
+
+	if status, body := testutil.Do(t, first, http.MethodPost, "/api/offers", token, map[string]interface{}{
+		"project_id":   project.ID,
+		"offer_amount": 25000,
+	}); body["code"] != "PAYMENT_REQUIRED" {
+		t.Fatalf("offer before paying: %d %v", status, body)
+	}
+
+	status, body = testutil.Do(t, first, http.MethodPost, "/api/payments/create-intent", token, nil)
+	if status != http.StatusCreated || body["provider"] != "fake" {
+		t.Fatalf("create intent: %d %v", status, body)
+	}
+	status, body = testutil.Do(t, second, http.MethodPost, "/api/payments/confirm", token, map[string]interface{}{
+		"payment_id": body["payment_id"],
+		"demo_mode":  true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("confirm payment on another instance: %d %v", status, body)
+	}
+
+	status, body = testutil.Do(t, second, http.MethodGet, projectPath, token, nil)
+	if status != http.StatusOK || body["full_access"] != true {
+		t.Fatalf("view project: %d %v", status, body)
+	}
+
+	status, body = testutil.Do(t, first, http.MethodPost, "/api/offers", token, map[string]interface{}{
+		"project_id":     project.ID,
+		"offer_amount":   25000,
+		"equity_request": 5,
+		"terms_notes":    "Standard terms",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("make offer: %d %v", status, body)
+	}
+
+	var offers int64
+	database.GetDB().Model(&models.InvestmentOffer{}).Where("investor_id = ? AND project_id = ?", investor.ID, project.ID).Count(&offers)
+	if offers != 1 {
+		t.Errorf("%d offers recorded, want 1", offers)
+	}
+}
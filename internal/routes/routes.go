@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/ukuvago/angel-platform/internal/authz"
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/handlers"
@@ -47,15 +48,26 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	documentService := services.NewDocumentService(cfg)
 	storageService := services.NewStorageService(cfg)
 	emailService := services.NewEmailService(cfg)
+	loginLimiter := services.NewLoginLimiter(cfg, emailService)
+	oidcService := services.NewOIDCService(cfg, authService)
+	kycService := services.NewKYCService(storageService)
+	organizationService := services.NewOrganizationService(authService)
+	collaboratorService := services.NewCollaboratorService(authService)
+	accountService := services.NewAccountService(authService, storageService)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, emailService)
+	authHandler := handlers.NewAuthHandler(authService, emailService, loginLimiter)
+	oidcHandler := handlers.NewOIDCHandler(cfg, oidcService, authService)
 	ndaHandler := handlers.NewNDAHandler(authService, documentService)
-	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, documentService)
 	projectHandler := handlers.NewProjectHandler(storageService, paymentService)
 	offerHandler := handlers.NewOfferHandler(emailService, documentService, authService)
 	termSheetHandler := handlers.NewTermSheetHandler(documentService, emailService, authService)
-	adminHandler := handlers.NewAdminHandler(emailService, authService)
+	adminHandler := handlers.NewAdminHandler(emailService, authService, paymentService, documentService, loginLimiter, accountService)
+	kycHandler := handlers.NewKYCHandler(kycService, emailService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, authService, emailService)
+	collaboratorHandler := handlers.NewCollaboratorHandler(collaboratorService, authService, emailService)
+	webhookHandler := handlers.NewWebhookHandler(paymentService)
 
 	// API routes
 	api := router.Group("/api")
@@ -77,17 +89,33 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/magic-link", authHandler.RequestMagicLink)
+			auth.POST("/magic-link/consume", authHandler.ConsumeMagicLink)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/2fa/verify", authHandler.VerifyTwoFactor)
 			auth.POST("/verify-email", authHandler.VerifyEmail)
 			auth.POST("/forgot-password", authHandler.ForgotPassword)
 			auth.POST("/reset-password", authHandler.ResetPassword)
 
+			// Sign-in with OpenID Connect providers
+			auth.GET("/oidc/providers", oidcHandler.ListProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+
 			// Protected auth routes
 			authProtected := auth.Group("")
 			authProtected.Use(middleware.AuthMiddleware(authService))
 			{
 				authProtected.GET("/me", authHandler.GetCurrentUser)
 				authProtected.PUT("/profile", authHandler.UpdateProfile)
-				authProtected.PUT("/password", authHandler.ChangePassword)
+				authProtected.PUT("/password", middleware.ForbidImpersonation(), authHandler.ChangePassword)
+				authProtected.POST("/resend-verification", authHandler.ResendVerification)
+				authProtected.POST("/logout", authHandler.Logout)
+				authProtected.POST("/logout-all", middleware.ForbidImpersonation(), authHandler.LogoutAll)
+				authProtected.POST("/2fa/setup", middleware.ForbidImpersonation(), authHandler.SetupTwoFactor)
+				authProtected.POST("/2fa/enable", middleware.ForbidImpersonation(), authHandler.EnableTwoFactor)
+				authProtected.POST("/2fa/disable", middleware.ForbidImpersonation(), authHandler.DisableTwoFactor)
+				authProtected.POST("/2fa/recovery-codes", middleware.ForbidImpersonation(), authHandler.RegenerateRecoveryCodes)
 			}
 		}
 
@@ -106,87 +134,151 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			{
 				// Get project with access control
 				projectsProtected.GET("/:id", middleware.CheckNDAStatus(), middleware.CheckPaymentStatus(paymentService), projectHandler.GetProject)
-
-				// Unified Project Management (Developer & Admin)
-				// Middleware removed here because Handler performs Role checks.
-				// For Create: Any Auth user can theoretically create? No, Investors shouldn't.
-				// We need a specific middleware or logic in handler.
-				// I will use `RequireDeveloperOrAdmin` if I can create it, or just handle it in handler.
-				// Let's modify handlers to strictly reject Investors if I remove the middleware.
-
-				// ACTUALLY: The easiest fix is to let `RequireDeveloper` pass if role is Admin.
-				// I'll check `internal/middleware/auth.go` first.
 			}
+
+			// Project management (developers own their projects, admins manage all)
+			projectsManage := projects.Group("")
+			projectsManage.Use(middleware.AuthMiddleware(authService))
+			registerProjectManagement(projectsManage, projectHandler, collaboratorHandler)
 		}
 
-		// Developer routes
+		// Developer routes. Reading projects is what sets developers and
+		// admins apart from investors, who read offers and term sheets too.
 		developer := api.Group("/developer")
-		developer.Use(middleware.AuthMiddleware(authService), middleware.RequireDeveloper())
+		developer.Use(middleware.AuthMiddleware(authService), middleware.Authorize(authz.ActionRead, authz.KindProject))
 		{
-			developer.GET("/projects", projectHandler.GetMyProjects)
 			developer.GET("/offers", offerHandler.GetMyOffers)
 			developer.GET("/termsheets", termSheetHandler.GetMyTermSheets)
 		}
 
+		developerProjects := api.Group("/developer/projects")
+		developerProjects.Use(middleware.AuthMiddleware(authService))
+		{
+			developerProjects.GET("", middleware.Authorize(authz.ActionRead, authz.KindProject), projectHandler.GetMyProjects)
+			registerProjectManagement(developerProjects, projectHandler, collaboratorHandler)
+		}
+
 		// NDA routes (investor only)
 		nda := api.Group("/nda")
-		nda.Use(middleware.AuthMiddleware(authService), middleware.RequireInvestor())
+		nda.Use(middleware.AuthMiddleware(authService), middleware.RequireVerifiedEmail())
+		{
+			nda.GET("/template", middleware.Authorize(authz.ActionRead, authz.KindNDA), ndaHandler.GetNDATemplate)
+			nda.GET("/status", middleware.Authorize(authz.ActionRead, authz.KindNDA), ndaHandler.GetNDAStatus)
+			nda.POST("/sign", middleware.Authorize(authz.ActionCreate, authz.KindNDA), middleware.ForbidImpersonation(), ndaHandler.SignNDA)
+			nda.GET("/download", middleware.Authorize(authz.ActionRead, authz.KindNDA), ndaHandler.DownloadNDA)
+		}
+
+		// Investor identity and accreditation checks
+		kyc := api.Group("/kyc")
+		kyc.Use(middleware.AuthMiddleware(authService), middleware.RequireVerifiedEmail())
 		{
-			nda.GET("/template", ndaHandler.GetNDATemplate)
-			nda.GET("/status", ndaHandler.GetNDAStatus)
-			nda.POST("/sign", ndaHandler.SignNDA)
-			nda.GET("/download", ndaHandler.DownloadNDA)
+			kyc.POST("", middleware.Authorize(authz.ActionCreate, authz.KindKYC), middleware.ForbidImpersonation(), kycHandler.SubmitKYC)
+			kyc.GET("", middleware.Authorize(authz.ActionRead, authz.KindKYC), kycHandler.GetKYCStatus)
+			kyc.GET("/documents/:id", middleware.Authorize(authz.ActionRead, authz.KindKYC), kycHandler.DownloadKYCDocument)
 		}
 
-		// Payment routes (investor only)
+		// Investment firms and startups. Accepting an invitation verifies the
+		// email it was sent to, so it does not need a verified email already.
+		organizations := api.Group("/organizations")
+		organizations.Use(middleware.AuthMiddleware(authService))
+		{
+			organizations.POST("", middleware.RequireVerifiedEmail(), middleware.Authorize(authz.ActionCreate, authz.KindOrganization), organizationHandler.CreateOrganization)
+			organizations.GET("/mine", middleware.Authorize(authz.ActionRead, authz.KindOrganization), organizationHandler.GetMyOrganization)
+			organizations.POST("/invitations/accept", middleware.Authorize(authz.ActionCreate, authz.KindOrganization), organizationHandler.AcceptInvitation)
+			organizations.GET("/:id", middleware.Authorize(authz.ActionRead, authz.KindOrganization), organizationHandler.GetOrganization)
+			organizations.POST("/:id/invitations", middleware.Authorize(authz.ActionManage, authz.KindOrganization), organizationHandler.InviteMember)
+			organizations.DELETE("/:id/invitations/:invitationId", middleware.Authorize(authz.ActionManage, authz.KindOrganization), organizationHandler.RevokeInvitation)
+			organizations.PUT("/:id/members/:userId", middleware.Authorize(authz.ActionManage, authz.KindOrganization), organizationHandler.UpdateMember)
+			organizations.DELETE("/:id/members/:userId", middleware.Authorize(authz.ActionLeave, authz.KindOrganization), organizationHandler.RemoveMember)
+		}
+
+		// Pricing plans (public)
+		api.GET("/payments/plans", paymentHandler.GetPlans)
+
+		// Payment routes (investors pay view fees)
 		payments := api.Group("/payments")
-		payments.Use(middleware.AuthMiddleware(authService), middleware.RequireInvestor())
+		payments.Use(middleware.AuthMiddleware(authService), middleware.RequireVerifiedEmail())
+		{
+			createPayment := middleware.Authorize(authz.ActionCreate, authz.KindPayment)
+			payments.POST("/create-intent", createPayment, middleware.ForbidImpersonation(), middleware.RequireNDA(), paymentHandler.CreatePaymentIntent)
+			payments.POST("/confirm", createPayment, middleware.ForbidImpersonation(), paymentHandler.ConfirmPayment)
+			payments.GET("/status", createPayment, paymentHandler.GetPaymentStatus)
+			payments.GET("/history", createPayment, paymentHandler.GetPaymentHistory)
+			payments.GET("/viewed", createPayment, paymentHandler.GetViewedProjects)
+			payments.GET("/ledger", createPayment, paymentHandler.GetCreditLedger)
+			payments.POST("/:id/refund", createPayment, middleware.Authorize(authz.ActionRefund, authz.KindPayment), middleware.ForbidImpersonation(), paymentHandler.RequestRefund)
+			payments.GET("/:id/invoice", middleware.Authorize(authz.ActionRead, authz.KindPayment), paymentHandler.DownloadInvoice)
+		}
+
+		// Webhook routes (public, verified by provider signature)
+		webhooks := api.Group("/webhooks")
 		{
-			payments.POST("/create-intent", middleware.RequireNDA(), paymentHandler.CreatePaymentIntent)
-			payments.POST("/confirm", paymentHandler.ConfirmPayment)
-			payments.GET("/status", paymentHandler.GetPaymentStatus)
-			payments.GET("/history", paymentHandler.GetPaymentHistory)
-			payments.GET("/viewed", paymentHandler.GetViewedProjects)
+			webhooks.POST("/:provider", webhookHandler.HandleWebhook)
 		}
 
 		// Offer routes
 		offers := api.Group("/offers")
-		offers.Use(middleware.AuthMiddleware(authService))
+		offers.Use(middleware.AuthMiddleware(authService), middleware.RequireVerifiedEmail())
 		{
 			// Investor routes
-			offers.POST("", middleware.RequireInvestor(), middleware.RequireNDA(), middleware.RequirePayment(paymentService), offerHandler.CreateOffer)
-			offers.DELETE("/:id", middleware.RequireInvestor(), offerHandler.WithdrawOffer)
+			offers.POST("", middleware.Authorize(authz.ActionCreate, authz.KindOffer), middleware.ForbidImpersonation(), middleware.RequireVerifiedInvestor(), middleware.RequireNDA(), middleware.RequirePayment(paymentService), offerHandler.CreateOffer)
+			offers.DELETE("/:id", middleware.Authorize(authz.ActionWithdraw, authz.KindOffer), middleware.ForbidImpersonation(), offerHandler.WithdrawOffer)
 
 			// Shared routes
-			offers.GET("", offerHandler.GetMyOffers)
-			offers.GET("/:id", offerHandler.GetOffer)
+			offers.GET("", middleware.Authorize(authz.ActionRead, authz.KindOffer), offerHandler.GetMyOffers)
+			offers.GET("/:id", middleware.Authorize(authz.ActionRead, authz.KindOffer), offerHandler.GetOffer)
 
 			// Developer routes
-			offers.POST("/:id/respond", middleware.RequireDeveloper(), offerHandler.RespondToOffer)
+			offers.POST("/:id/respond", middleware.Authorize(authz.ActionRespond, authz.KindOffer), middleware.ForbidImpersonation(), offerHandler.RespondToOffer)
 		}
 
 		// Term sheet routes
 		termsheets := api.Group("/termsheets")
-		termsheets.Use(middleware.AuthMiddleware(authService))
+		termsheets.Use(middleware.AuthMiddleware(authService), middleware.RequireVerifiedEmail())
 		{
-			termsheets.GET("", termSheetHandler.GetMyTermSheets)
-			termsheets.GET("/:id", termSheetHandler.GetTermSheet)
-			termsheets.POST("/:id/sign", termSheetHandler.SignTermSheet)
-			termsheets.GET("/:id/download", termSheetHandler.DownloadTermSheet)
+			termsheets.GET("", middleware.Authorize(authz.ActionRead, authz.KindTermSheet), termSheetHandler.GetMyTermSheets)
+			termsheets.GET("/:id", middleware.Authorize(authz.ActionRead, authz.KindTermSheet), termSheetHandler.GetTermSheet)
+			termsheets.POST("/:id/sign", middleware.Authorize(authz.ActionSign, authz.KindTermSheet), middleware.ForbidImpersonation(), middleware.RequireVerifiedInvestor(), middleware.RequireTwoFactor(cfg.RequireSigning2FA), termSheetHandler.SignTermSheet)
+			termsheets.GET("/:id/download", middleware.Authorize(authz.ActionRead, authz.KindTermSheet), termSheetHandler.DownloadTermSheet)
 		}
 
 		// Admin routes
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(authService), middleware.RequireAdmin())
+		admin.Use(middleware.AuthMiddleware(authService), middleware.Authorize(authz.ActionManage, authz.KindPlatform), middleware.RequireTwoFactor(cfg.RequireAdmin2FA))
 		{
 			admin.GET("/stats", adminHandler.GetDashboardStats)
 			admin.GET("/users", adminHandler.ListAllUsers)
+			admin.POST("/users/:id/credits", adminHandler.GrantCredits)
+			admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+			admin.POST("/users/:id/suspend", adminHandler.SuspendUser)
+			admin.POST("/users/:id/unsuspend", adminHandler.UnsuspendUser)
+			admin.PUT("/users/:id/role", adminHandler.ChangeUserRole)
+			admin.POST("/users/:id/reset-password", adminHandler.ForcePasswordReset)
+			admin.POST("/users/:id/revoke-sessions", adminHandler.RevokeUserSessions)
+			admin.DELETE("/users/:id", adminHandler.DeleteUser)
+			admin.POST("/users/:id/impersonate", adminHandler.ImpersonateUser)
 			admin.GET("/projects", adminHandler.ListAllProjects)
 			admin.GET("/projects/pending", adminHandler.GetPendingProjects)
 			admin.GET("/projects/all", adminHandler.GetAllProjects)
-			admin.POST("/projects/:id/approve", adminHandler.ApproveProject)
+			admin.POST("/projects/:id/approve", middleware.Authorize(authz.ActionApprove, authz.KindProject), adminHandler.ApproveProject)
+			admin.GET("/kyc", middleware.Authorize(authz.ActionApprove, authz.KindKYC), kycHandler.ListKYCQueue)
+			admin.GET("/kyc/:id", middleware.Authorize(authz.ActionApprove, authz.KindKYC), kycHandler.GetKYCSubmission)
+			admin.POST("/kyc/:id/review", middleware.Authorize(authz.ActionApprove, authz.KindKYC), kycHandler.ReviewKYC)
+			admin.GET("/kyc/documents/:id", middleware.Authorize(authz.ActionApprove, authz.KindKYC), kycHandler.DownloadKYCDocument)
 			admin.GET("/offers", adminHandler.ListAllOffers)
 			admin.GET("/payments", adminHandler.ListAllPayments)
+			admin.GET("/payments/:id/ledger", adminHandler.GetPaymentLedger)
+			admin.POST("/payments/:id/refund", adminHandler.RefundPayment)
+			admin.GET("/invoices/export", adminHandler.ExportInvoices)
+			admin.GET("/plans", adminHandler.ListPricingPlans)
+			admin.POST("/plans", adminHandler.CreatePricingPlan)
+			admin.PUT("/plans/:id", adminHandler.UpdatePricingPlan)
+			admin.DELETE("/plans/:id", adminHandler.DeletePricingPlan)
+			admin.GET("/coupons", adminHandler.ListCoupons)
+			admin.POST("/coupons", adminHandler.CreateCoupon)
+			admin.PUT("/coupons/:id", adminHandler.UpdateCoupon)
+			admin.DELETE("/coupons/:id", adminHandler.DeleteCoupon)
+			admin.GET("/coupons/:id/redemptions", adminHandler.GetCouponRedemptions)
 			admin.POST("/categories", adminHandler.CreateCategory)
 			admin.PUT("/categories/:id", adminHandler.UpdateCategory)
 			admin.DELETE("/categories/:id", adminHandler.DeleteCategory)
@@ -204,10 +296,33 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	return router
 }
 
+// registerProjectManagement mounts the project create/edit/submit, image and
+// collaborator routes on an authenticated group
+func registerProjectManagement(group *gin.RouterGroup, projectHandler *handlers.ProjectHandler, collaboratorHandler *handlers.CollaboratorHandler) {
+	update := middleware.Authorize(authz.ActionUpdate, authz.KindProject)
+	group.POST("", middleware.Authorize(authz.ActionCreate, authz.KindProject), projectHandler.CreateProject)
+	group.PUT("/:id", update, projectHandler.UpdateProject)
+	group.POST("/:id/submit", middleware.Authorize(authz.ActionSubmit, authz.KindProject), projectHandler.SubmitProject)
+	group.POST("/:id/images", update, projectHandler.UploadProjectImage)
+	group.DELETE("/:id/images/:imageId", update, projectHandler.DeleteProjectImage)
+
+	// Collaborators. Accepting an invitation verifies the email it was sent
+	// to, and collaborators may leave a project they only read.
+	read := middleware.Authorize(authz.ActionRead, authz.KindProject)
+	manage := middleware.Authorize(authz.ActionManage, authz.KindProject)
+	group.POST("/invitations/accept", read, collaboratorHandler.AcceptCollaboratorInvitation)
+	group.GET("/:id/collaborators", read, collaboratorHandler.ListCollaborators)
+	group.POST("/:id/collaborators/invitations", manage, collaboratorHandler.InviteCollaborator)
+	group.DELETE("/:id/collaborators/invitations/:invitationId", manage, collaboratorHandler.RevokeCollaboratorInvitation)
+	group.PUT("/:id/collaborators/:userId", manage, collaboratorHandler.UpdateCollaborator)
+	group.DELETE("/:id/collaborators/:userId", read, collaboratorHandler.RemoveCollaborator)
+	group.POST("/:id/transfer", manage, collaboratorHandler.TransferProject)
+}
+
 // SeedAdminUser creates a default admin user if none exists
 func SeedAdminUser(cfg *config.Config, authService *services.AuthService) error {
 	// Check if admin exists
-	_, _, err := authService.Login(cfg.AdminEmail, "admin123")
+	_, err := authService.Authenticate(cfg.AdminEmail, "admin123")
 	if err == nil {
 		return nil // Admin exists
 	}
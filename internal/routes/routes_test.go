@@ -0,0 +1,337 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/authz"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// anonymous stands for a caller without a token in the tests below
+const anonymous models.UserRole = ""
+
+// routeNeed is a route policy the Authorize middleware checks
+type routeNeed struct {
+	action authz.Action
+	kind   authz.Kind
+}
+
+// routeAccess is who may reach a route: anyone if it is public, otherwise
+// signed-in users whose role passes every one of its route policies
+type routeAccess struct {
+	public bool
+	needs  []routeNeed
+}
+
+// routeTable lists the access of every API route SetupRouter mounts, keyed
+// by method and path
+func routeTable() map[string]routeAccess {
+	public := routeAccess{public: true}
+	signedIn := routeAccess{}
+	need := func(action authz.Action, kind authz.Kind) routeAccess {
+		return routeAccess{needs: []routeNeed{{action, kind}}}
+	}
+	admin := func(needs ...routeNeed) routeAccess {
+		return routeAccess{needs: append([]routeNeed{{authz.ActionManage, authz.KindPlatform}}, needs...)}
+	}
+	approveKYC := routeNeed{authz.ActionApprove, authz.KindKYC}
+	createPayment := routeNeed{authz.ActionCreate, authz.KindPayment}
+
+	table := map[string]routeAccess{
+		"POST /api/auth/register":               public,
+		"POST /api/auth/login":                  public,
+		"POST /api/auth/magic-link":             public,
+		"POST /api/auth/magic-link/consume":     public,
+		"POST /api/auth/refresh":                public,
+		"POST /api/auth/2fa/verify":             public,
+		"POST /api/auth/verify-email":           public,
+		"POST /api/auth/forgot-password":        public,
+		"POST /api/auth/reset-password":         public,
+		"GET /api/auth/oidc/providers":          public,
+		"GET /api/auth/oidc/:provider/login":    public,
+		"GET /api/auth/oidc/:provider/callback": public,
+		"GET /api/auth/me":                      signedIn,
+		"PUT /api/auth/profile":                 signedIn,
+		"PUT /api/auth/password":                signedIn,
+		"POST /api/auth/resend-verification":    signedIn,
+		"POST /api/auth/logout":                 signedIn,
+		"POST /api/auth/logout-all":             signedIn,
+		"POST /api/auth/2fa/setup":              signedIn,
+		"POST /api/auth/2fa/enable":             signedIn,
+		"POST /api/auth/2fa/disable":            signedIn,
+		"POST /api/auth/2fa/recovery-codes":     signedIn,
+
+		"GET /api/categories":          public,
+		"GET /api/projects":            public,
+		"GET /api/projects/:id":        signedIn,
+		"GET /api/payments/plans":      public,
+		"POST /api/webhooks/:provider": public,
+
+		"GET /api/developer/offers":     need(authz.ActionRead, authz.KindProject),
+		"GET /api/developer/termsheets": need(authz.ActionRead, authz.KindProject),
+		"GET /api/developer/projects":   need(authz.ActionRead, authz.KindProject),
+
+		"GET /api/nda/template": need(authz.ActionRead, authz.KindNDA),
+		"GET /api/nda/status":   need(authz.ActionRead, authz.KindNDA),
+		"POST /api/nda/sign":    need(authz.ActionCreate, authz.KindNDA),
+		"GET /api/nda/download": need(authz.ActionRead, authz.KindNDA),
+
+		"POST /api/kyc":              need(authz.ActionCreate, authz.KindKYC),
+		"GET /api/kyc":               need(authz.ActionRead, authz.KindKYC),
+		"GET /api/kyc/documents/:id": need(authz.ActionRead, authz.KindKYC),
+
+		"POST /api/organizations":                                 need(authz.ActionCreate, authz.KindOrganization),
+		"GET /api/organizations/mine":                             need(authz.ActionRead, authz.KindOrganization),
+		"POST /api/organizations/invitations/accept":              need(authz.ActionCreate, authz.KindOrganization),
+		"GET /api/organizations/:id":                              need(authz.ActionRead, authz.KindOrganization),
+		"POST /api/organizations/:id/invitations":                 need(authz.ActionManage, authz.KindOrganization),
+		"DELETE /api/organizations/:id/invitations/:invitationId": need(authz.ActionManage, authz.KindOrganization),
+		"PUT /api/organizations/:id/members/:userId":              need(authz.ActionManage, authz.KindOrganization),
+		"DELETE /api/organizations/:id/members/:userId":           need(authz.ActionLeave, authz.KindOrganization),
+
+		"POST /api/payments/create-intent": {needs: []routeNeed{createPayment}},
+		"POST /api/payments/confirm":       {needs: []routeNeed{createPayment}},
+		"GET /api/payments/status":         {needs: []routeNeed{createPayment}},
+		"GET /api/payments/history":        {needs: []routeNeed{createPayment}},
+		"GET /api/payments/viewed":         {needs: []routeNeed{createPayment}},
+		"GET /api/payments/ledger":         {needs: []routeNeed{createPayment}},
+		"POST /api/payments/:id/refund":    {needs: []routeNeed{createPayment, {authz.ActionRefund, authz.KindPayment}}},
+		"GET /api/payments/:id/invoice":    need(authz.ActionRead, authz.KindPayment),
+
+		"POST /api/offers":             need(authz.ActionCreate, authz.KindOffer),
+		"DELETE /api/offers/:id":       need(authz.ActionWithdraw, authz.KindOffer),
+		"GET /api/offers":              need(authz.ActionRead, authz.KindOffer),
+		"GET /api/offers/:id":          need(authz.ActionRead, authz.KindOffer),
+		"POST /api/offers/:id/respond": need(authz.ActionRespond, authz.KindOffer),
+
+		"GET /api/termsheets":              need(authz.ActionRead, authz.KindTermSheet),
+		"GET /api/termsheets/:id":          need(authz.ActionRead, authz.KindTermSheet),
+		"POST /api/termsheets/:id/sign":    need(authz.ActionSign, authz.KindTermSheet),
+		"GET /api/termsheets/:id/download": need(authz.ActionRead, authz.KindTermSheet),
+
+		"GET /api/admin/kyc":                   admin(approveKYC),
+		"GET /api/admin/kyc/:id":               admin(approveKYC),
+		"POST /api/admin/kyc/:id/review":       admin(approveKYC),
+		"GET /api/admin/kyc/documents/:id":     admin(approveKYC),
+		"POST /api/admin/projects/:id/approve": admin(routeNeed{authz.ActionApprove, authz.KindProject}),
+	}
+
+	// Project management is mounted under both prefixes
+	for _, prefix := range []string{"/api/projects", "/api/developer/projects"} {
+		for route, access := range map[string]routeAccess{
+			"POST ":                               need(authz.ActionCreate, authz.KindProject),
+			"PUT /:id":                            need(authz.ActionUpdate, authz.KindProject),
+			"POST /:id/submit":                    need(authz.ActionSubmit, authz.KindProject),
+			"POST /:id/images":                    need(authz.ActionUpdate, authz.KindProject),
+			"DELETE /:id/images/:imageId":         need(authz.ActionUpdate, authz.KindProject),
+			"POST /invitations/accept":            need(authz.ActionRead, authz.KindProject),
+			"GET /:id/collaborators":              need(authz.ActionRead, authz.KindProject),
+			"POST /:id/collaborators/invitations": need(authz.ActionManage, authz.KindProject),
+			"DELETE /:id/collaborators/invitations/:invitationId": need(authz.ActionManage, authz.KindProject),
+			"PUT /:id/collaborators/:userId":                      need(authz.ActionManage, authz.KindProject),
+			"DELETE /:id/collaborators/:userId":                   need(authz.ActionRead, authz.KindProject),
+			"POST /:id/transfer":                                  need(authz.ActionManage, authz.KindProject),
+		} {
+			method, path, _ := strings.Cut(route, " ")
+			table[method+" "+prefix+path] = access
+		}
+	}
+
+	// The rest of the admin area only needs the platform policy
+	for _, route := range []string{
+		"GET /api/admin/stats",
+		"GET /api/admin/users",
+		"POST /api/admin/users/:id/credits",
+		"POST /api/admin/users/:id/unlock",
+		"POST /api/admin/users/:id/suspend",
+		"POST /api/admin/users/:id/unsuspend",
+		"PUT /api/admin/users/:id/role",
+		"POST /api/admin/users/:id/reset-password",
+		"POST /api/admin/users/:id/revoke-sessions",
+		"DELETE /api/admin/users/:id",
+		"POST /api/admin/users/:id/impersonate",
+		"GET /api/admin/projects",
+		"GET /api/admin/projects/pending",
+		"GET /api/admin/projects/all",
+		"GET /api/admin/offers",
+		"GET /api/admin/payments",
+		"GET /api/admin/payments/:id/ledger",
+		"POST /api/admin/payments/:id/refund",
+		"GET /api/admin/invoices/export",
+		"GET /api/admin/plans",
+		"POST /api/admin/plans",
+		"PUT /api/admin/plans/:id",
+		"DELETE /api/admin/plans/:id",
+		"GET /api/admin/coupons",
+		"POST /api/admin/coupons",
+		"PUT /api/admin/coupons/:id",
+		"DELETE /api/admin/coupons/:id",
+		"GET /api/admin/coupons/:id/redemptions",
+		"POST /api/admin/categories",
+		"PUT /api/admin/categories/:id",
+		"DELETE /api/admin/categories/:id",
+	} {
+		table[route] = admin()
+	}
+	return table
+}
+
+// TestRoutePolicies sends every API route a request as each role, and checks
+// it is let through exactly when authz.Policies grant the role the route's
+// policies. A denied caller gets the auth middleware's 401 or 403, whatever
+// the handler would go on to say about the request itself.
+func TestRoutePolicies(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+	table := routeTable()
+
+	mounted := map[string]bool{}
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		mounted[key] = true
+		access, ok := table[key]
+		if !ok {
+			t.Errorf("%s has no access listed in routeTable", key)
+			continue
+		}
+
+		segments := strings.Split(route.Path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = uuid.NewString()
+			}
+		}
+		path := strings.Join(segments, "/")
+
+		for _, role := range []models.UserRole{anonymous, models.RoleInvestor, models.RoleDeveloper, models.RoleAdmin} {
+			allowed := access.public
+			token := ""
+			if role != anonymous {
+				allowed = true
+				for _, n := range access.needs {
+					allowed = allowed && authz.Allowed(role, n.action, n.kind)
+				}
+				// Each request gets its own user, as some sign the caller out
+				token = testutil.Token(t, cfg, testutil.User(t, role))
+			}
+
+			status, body := testutil.Do(t, router, route.Method, path, token, nil)
+			denied := body["error"] == "Authorization header required" || body["error"] == "Insufficient permissions"
+			if allowed == denied {
+				t.Errorf("%s as %q: got %d %v, want allowed=%v", key, role, status, body, allowed)
+			}
+		}
+	}
+
+	for key := range table {
+		if !mounted[key] {
+			t.Errorf("%s is listed in routeTable but not mounted", key)
+		}
+	}
+}
+
+// TestDeveloperListsForbidInvestors checks the developer offer and term sheet
+// lists are refused to investors, who have lists of their own
+func TestDeveloperListsForbidInvestors(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+	investor := testutil.Token(t, cfg, testutil.User(t, models.RoleInvestor))
+	developer := testutil.Token(t, cfg, testutil.User(t, models.RoleDeveloper))
+
+	for _, path := range []string{"/api/developer/offers", "/api/developer/termsheets"} {
+		if status, body := testutil.Do(t, router, http.MethodGet, path, investor, nil); status != http.StatusForbidden {
+			t.Errorf("%s as investor: %d %v, want 403", path, status, body)
+		}
+		if status, body := testutil.Do(t, router, http.MethodGet, path, developer, nil); status != http.StatusOK {
+			t.Errorf("%s as developer: %d %v, want 200", path, status, body)
+		}
+	}
+}
+
+// TestGetProjectFullAccess checks who sees a project in full rather than
+// its public listing
+func TestGetProjectFullAccess(t *testing.T) {
+	cfg := testutil.Database(t)
+	router := SetupRouter(cfg)
+	paymentService := services.NewPaymentService(cfg)
+
+	owner := testutil.User(t, models.RoleDeveloper)
+	viewer := testutil.User(t, models.RoleDeveloper)
+	stranger := testutil.User(t, models.RoleDeveloper)
+	admin := testutil.User(t, models.RoleAdmin)
+	paying := testutil.User(t, models.RoleInvestor)
+	unpaid := testutil.User(t, models.RoleInvestor)
+
+	approved := testutil.Project(t, owner, models.ProjectStatusApproved)
+	draft := testutil.Project(t, owner, models.ProjectStatusDraft)
+	for _, project := range []*models.Project{approved, draft} {
+		if err := database.GetDB().Create(&models.ProjectCollaborator{
+			ProjectID:  project.ID,
+			UserID:     viewer.ID,
+			Permission: models.ProjectPermissionViewer,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := paymentService.GrantCredits(admin.ID, paying.ID, 2, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		project *models.Project
+		status  int
+		full    bool
+	}{
+		{"anonymous", nil, approved, http.StatusUnauthorized, false},
+		{"owner", owner, approved, http.StatusOK, true},
+		{"owner of draft", owner, draft, http.StatusOK, true},
+		{"viewer collaborator", viewer, approved, http.StatusOK, true},
+		{"other developer", stranger, approved, http.StatusOK, false},
+		{"other developer on draft", stranger, draft, http.StatusOK, false},
+		{"admin", admin, draft, http.StatusOK, true},
+		{"investor with credits", paying, approved, http.StatusOK, true},
+		{"investor who already viewed", paying, approved, http.StatusOK, true},
+		{"investor on draft", paying, draft, http.StatusOK, false},
+		{"investor without credits", unpaid, approved, http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := ""
+			if tt.user != nil {
+				token = testutil.Token(t, cfg, tt.user)
+			}
+
+			status, body := testutil.Do(t, router, http.MethodGet, "/api/projects/"+tt.project.ID.String(), token, nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			project, _ := body["project"].(map[string]interface{})
+			_, hasDescription := project["description"]
+			if body["full_access"] != tt.full || hasDescription != tt.full {
+				t.Errorf("full_access = %v, description shown = %v, want %v", body["full_access"], hasDescription, tt.full)
+			}
+		})
+	}
+
+	var views int64
+	database.GetDB().Model(&models.ProjectView{}).Where("investor_id = ?", paying.ID).Count(&views)
+	if views != 1 {
+		t.Errorf("paying investor has %d views, want 1", views)
+	}
+}
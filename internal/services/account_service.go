@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrCannotManageSelf  = errors.New("admins cannot do this to their own account")
+	ErrAlreadySuspended  = errors.New("account is already suspended")
+	ErrNotSuspended      = errors.New("account is not suspended")
+	ErrInvalidRole       = errors.New("role must be investor, developer or admin")
+	ErrRoleUnchanged     = errors.New("user already has this role")
+	ErrRoleChangeBlocked = errors.New("user must leave their organization and projects before their role changes")
+)
+
+// AccountService carries out what admins do to other users' accounts. Every
+// action is recorded in the audit log against the acting admin.
+type AccountService struct {
+	authService    *AuthService
+	storageService *StorageService
+}
+
+func NewAccountService(authService *AuthService, storageService *StorageService) *AccountService {
+	return &AccountService{authService: authService, storageService: storageService}
+}
+
+// Suspend stops a user from signing in or using tokens already issued, until
+// they are unsuspended. Their sessions are kept so unsuspending restores them.
+func (s *AccountService) Suspend(adminID, userID uuid.UUID, reason, ipAddress string) (*models.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotManageSelf
+	}
+
+	return s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND suspended_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"suspended_at":   time.Now(),
+				"suspend_reason": reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadySuspended
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditUserSuspended,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+			Details:   reason,
+		})
+		return nil
+	})
+}
+
+// Unsuspend lets a suspended user back in
+func (s *AccountService) Unsuspend(adminID, userID uuid.UUID, ipAddress string) (*models.User, error) {
+	return s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND suspended_at IS NOT NULL", userID).
+			Updates(map[string]interface{}{
+				"suspended_at":   nil,
+				"suspend_reason": "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotSuspended
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditUserUnsuspended,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+		})
+		return nil
+	})
+}
+
+// ChangeRole moves a user to another role. Organisations and project
+// collaboration depend on the role, so users in either must leave first.
+// Tokens carry the role, so the user is signed out everywhere.
+func (s *AccountService) ChangeRole(adminID, userID uuid.UUID, role models.UserRole, ipAddress string) (*models.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotManageSelf
+	}
+	if role != models.RoleInvestor && role != models.RoleDeveloper && role != models.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+
+	return s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		if user.Role == role {
+			return ErrRoleUnchanged
+		}
+
+		var memberships, collaborations int64
+		if err := tx.Model(&models.OrganizationMember{}).Where("user_id = ?", userID).Count(&memberships).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProjectCollaborator{}).Where("user_id = ?", userID).Count(&collaborations).Error; err != nil {
+			return err
+		}
+		if memberships > 0 || collaborations > 0 {
+			return ErrRoleChangeBlocked
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND role = ?", userID, user.Role).
+			Update("role", role)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRoleUnchanged
+		}
+
+		if _, err := revokeSessions(tx, userID, uuid.Nil); err != nil {
+			return err
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditRoleChanged,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+			Details:   string(user.Role) + " -> " + string(role),
+		})
+		return nil
+	})
+}
+
+// ForcePasswordReset clears a user's password and signs them out
+// everywhere. The returned token is the one to email them so they can set a
+// new password.
+func (s *AccountService) ForcePasswordReset(adminID, userID uuid.UUID, ipAddress string) (*models.User, string, error) {
+	var token string
+	user, err := s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		// An empty hash matches no password
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", "").Error; err != nil {
+			return err
+		}
+
+		var err error
+		token, err = s.authService.issueUserToken(tx, userID, models.TokenPurposePasswordReset, passwordResetTTL, ipAddress)
+		if err != nil {
+			return err
+		}
+
+		if _, err := revokeSessions(tx, userID, uuid.Nil); err != nil {
+			return err
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditPasswordResetForced,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// RevokeSessions signs a user out everywhere and returns how many sessions
+// were ended
+func (s *AccountService) RevokeSessions(adminID, userID uuid.UUID, ipAddress string) (int64, error) {
+	var revoked int64
+	_, err := s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		var err error
+		revoked, err = revokeSessions(tx, userID, uuid.Nil)
+		if err != nil {
+			return err
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditSessionsRevoked,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+		})
+		return nil
+	})
+	return revoked, err
+}
+
+// Delete anonymises a user's personal details, removes their sign-in
+// methods, memberships, pending invitations and KYC documents, and
+// soft-deletes the account. Records others rely on, such as projects, offers,
+// NDAs, payments and the outcome of KYC reviews, stay attached to the
+// anonymised account.
+func (s *AccountService) Delete(adminID, userID uuid.UUID, ipAddress string) error {
+	if adminID == userID {
+		return ErrCannotManageSelf
+	}
+
+	var submissions []uuid.UUID
+	_, err := s.update(userID, func(tx *gorm.DB, user *models.User) error {
+		var member models.OrganizationMember
+		if err := tx.First(&member, "user_id = ?", userID).Error; err == nil {
+			if err := removeMember(tx, &member); err != nil {
+				return err
+			}
+		}
+
+		// The owner row stays, as every project keeps one
+		if err := tx.Where("user_id = ? AND permission <> ?", userID, models.ProjectPermissionOwner).
+			Delete(&models.ProjectCollaborator{}).Error; err != nil {
+			return err
+		}
+
+		email := strings.ToLower(user.Email)
+		if err := tx.Where("email = ? AND accepted_at IS NULL", email).Delete(&models.OrganizationInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("email = ? AND accepted_at IS NULL", email).Delete(&models.ProjectInvitation{}).Error; err != nil {
+			return err
+		}
+
+		for _, record := range []interface{}{&models.UserIdentity{}, &models.RecoveryCode{}, &models.UserToken{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(record).Error; err != nil {
+				return err
+			}
+		}
+		if _, err := revokeSessions(tx, userID, uuid.Nil); err != nil {
+			return err
+		}
+
+		// Only the review outcome is kept of KYC submissions
+		if err := tx.Model(&models.KYCSubmission{}).Where("investor_id = ?", userID).Pluck("id", &submissions).Error; err != nil {
+			return err
+		}
+		if len(submissions) > 0 {
+			if err := tx.Model(&models.KYCDocument{}).Where("submission_id IN ?", submissions).
+				Updates(map[string]interface{}{"file_path": "", "file_name": ""}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"email":          "deleted-" + userID.String() + "@deleted.invalid",
+			"password_hash":  "",
+			"first_name":     "Deleted",
+			"last_name":      "User",
+			"phone":          "",
+			"company_name":   "",
+			"bio":            "",
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.User{}, "id = ?", userID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditUserDeleted,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files are only removed once the records no longer point at them
+	for _, id := range submissions {
+		if err := s.storageService.DeleteKYCSubmission(id); err != nil {
+			log.Printf("Failed to delete KYC documents of submission %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// update loads the user and runs fn in a transaction, returning the user as
+// it is afterwards
+func (s *AccountService) update(userID uuid.UUID, fn func(tx *gorm.DB, user *models.User) error) (*models.User, error) {
+	var user models.User
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return ErrUserNotFound
+		}
+		if err := fn(tx, &user); err != nil {
+			return err
+		}
+		return tx.Unscoped().First(&user, "id = ?", userID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestDeleteRemovesKYCDocuments checks a deleted investor's KYC files are
+// removed while the outcome of the review is kept
+func TestDeleteRemovesKYCDocuments(t *testing.T) {
+	cfg := testutil.Database(t)
+	storageService := services.NewStorageService(cfg)
+	accountService := services.NewAccountService(services.NewAuthService(cfg), storageService)
+	kycService := services.NewKYCService(storageService)
+
+	admin := testutil.User(t, models.RoleAdmin)
+	investor := testutil.User(t, models.RoleInvestor)
+
+	reviewedAt := time.Now()
+	submission := &models.KYCSubmission{
+		ID:           uuid.New(),
+		InvestorID:   investor.ID,
+		Status:       models.KYCStatusApproved,
+		ReviewedByID: &admin.ID,
+		ReviewedAt:   &reviewedAt,
+	}
+	path := filepath.Join(submission.ID.String(), "passport.pdf")
+	if err := os.MkdirAll(filepath.Join(cfg.KYCUploadDir, submission.ID.String()), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.KYCUploadDir, path), []byte("%PDF-1.4"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	submission.Documents = []models.KYCDocument{{Type: models.KYCDocumentIdentity, FilePath: path, FileName: "passport.pdf"}}
+	if err := database.GetDB().Create(submission).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := accountService.Delete(admin.ID, investor.ID, "127.0.0.1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.KYCUploadDir, submission.ID.String())); !os.IsNotExist(err) {
+		t.Errorf("KYC files are still stored: %v", err)
+	}
+
+	var document models.KYCDocument
+	database.GetDB().First(&document, "submission_id = ?", submission.ID)
+	if document.FilePath != "" || document.FileName != "" {
+		t.Errorf("document still names %q, %q", document.FilePath, document.FileName)
+	}
+	if _, err := kycService.GetDocument(document.ID); !errors.Is(err, services.ErrKYCDocumentNotFound) {
+		t.Errorf("get deleted document: %v, want ErrKYCDocumentNotFound", err)
+	}
+
+	var stored models.KYCSubmission
+	database.GetDB().First(&stored, "id = ?", submission.ID)
+	if stored.Status != models.KYCStatusApproved || stored.ReviewedByID == nil || *stored.ReviewedByID != admin.ID {
+		t.Errorf("review outcome lost: %+v", stored)
+	}
+}
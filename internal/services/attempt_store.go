@@ -0,0 +1,159 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttemptStore counts attempts per key for the LoginLimiter. The memory store
+// suits a single instance; the database store shares counts between instances.
+type AttemptStore interface {
+	// Fail records an attempt against key and returns how many have been made
+	// since the series began. A series that began before since starts over.
+	Fail(key string, now, since time.Time) (int, error)
+
+	// Block stops key being tried until the given time
+	Block(key string, until time.Time) error
+
+	// BlockedUntil returns when key may be tried again, zero if it may now
+	BlockedUntil(key string) (time.Time, error)
+
+	// Reset forgets the key's attempts and block
+	Reset(key string) error
+}
+
+// memorySweepInterval is how often the memory store forgets keys whose
+// attempts and block have both run out
+const memorySweepInterval = time.Minute
+
+// MemoryAttemptStore keeps attempts in process memory
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*models.LoginAttempt
+	swept    time.Time // Last time stale keys were removed
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]*models.LoginAttempt)}
+}
+
+func (s *MemoryAttemptStore) Fail(key string, now, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) >= memorySweepInterval {
+		s.sweep(now, since)
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &models.LoginAttempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	if attempt.Failures == 0 || attempt.FirstFailureAt.Before(since) {
+		attempt.Failures = 0
+		attempt.FirstFailureAt = now
+	}
+	attempt.Failures++
+	return attempt.Failures, nil
+}
+
+// sweep removes keys whose series began before since and which are not
+// blocked past now. Their next failure would start over anyway.
+func (s *MemoryAttemptStore) sweep(now, since time.Time) {
+	for key, attempt := range s.attempts {
+		if attempt.FirstFailureAt.Before(since) && (attempt.BlockedUntil == nil || !attempt.BlockedUntil.After(now)) {
+			delete(s.attempts, key)
+		}
+	}
+	s.swept = now
+}
+
+func (s *MemoryAttemptStore) Block(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &models.LoginAttempt{Key: key, FirstFailureAt: time.Now()}
+		s.attempts[key] = attempt
+	}
+	attempt.BlockedUntil = &until
+	return nil
+}
+
+func (s *MemoryAttemptStore) BlockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok && attempt.BlockedUntil != nil {
+		return *attempt.BlockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// DatabaseAttemptStore keeps attempts in the login_attempts table
+type DatabaseAttemptStore struct{}
+
+func NewDatabaseAttemptStore() *DatabaseAttemptStore {
+	return &DatabaseAttemptStore{}
+}
+
+// Fail counts the attempt with a single upsert, so concurrent attempts on
+// different instances are all counted
+func (s *DatabaseAttemptStore) Fail(key string, now, since time.Time) (int, error) {
+	db := database.GetDB()
+
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN login_attempts.first_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", since)},
+			{Column: clause.Column{Name: "first_failure_at"}, Value: gorm.Expr("CASE WHEN login_attempts.first_failure_at < ? THEN ? ELSE login_attempts.first_failure_at END", since, now)},
+		},
+	}).Create(&models.LoginAttempt{Key: key, Failures: 1, FirstFailureAt: now}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var attempt models.LoginAttempt
+	if err := db.First(&attempt, "key = ?", key).Error; err != nil {
+		return 0, err
+	}
+	return attempt.Failures, nil
+}
+
+func (s *DatabaseAttemptStore) Block(key string, until time.Time) error {
+	return database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"blocked_until": until}),
+	}).Create(&models.LoginAttempt{Key: key, FirstFailureAt: time.Now(), BlockedUntil: &until}).Error
+}
+
+func (s *DatabaseAttemptStore) BlockedUntil(key string) (time.Time, error) {
+	var attempt models.LoginAttempt
+	err := database.GetDB().First(&attempt, "key = ?", key).Error
+	if err == gorm.ErrRecordNotFound || (err == nil && attempt.BlockedUntil == nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *attempt.BlockedUntil, nil
+}
+
+func (s *DatabaseAttemptStore) Reset(key string) error {
+	return database.GetDB().Delete(&models.LoginAttempt{}, "key = ?", key).Error
+}
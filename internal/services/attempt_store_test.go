@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryAttemptStoreSweep checks keys are forgotten once their attempts
+// and block have run out, and kept while either still counts
+func TestMemoryAttemptStoreSweep(t *testing.T) {
+	store := NewMemoryAttemptStore()
+	start := time.Now()
+	fail := func(key string, at time.Time) {
+		store.Fail(key, at, at.Add(-time.Hour))
+	}
+	kept := func(key string) bool {
+		_, ok := store.attempts[key]
+		return ok
+	}
+
+	fail("ip:10.0.0.1", start)
+	fail("account:blocked", start)
+	store.Block("account:blocked", start.Add(3*time.Hour))
+
+	fail("account:other", start.Add(30*time.Minute))
+	if !kept("ip:10.0.0.1") {
+		t.Fatal("a key was forgotten within its window")
+	}
+
+	fail("account:recent", start.Add(2*time.Hour))
+	for key, want := range map[string]bool{
+		"ip:10.0.0.1":     false,
+		"account:other":   false,
+		"account:blocked": true,
+		"account:recent":  true,
+	} {
+		if got := kept(key); got != want {
+			t.Errorf("%s kept = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"log"
+
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// recordAudit appends an audit entry. A failure to record is logged rather
+// than failing the action being audited.
+func recordAudit(db *gorm.DB, entry *models.AuditLog) {
+	if err := db.Create(entry).Error; err != nil {
+		log.Printf("Failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
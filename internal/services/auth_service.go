@@ -12,6 +12,7 @@ import (
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/models"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthService struct {
@@ -22,11 +23,26 @@ func NewAuthService(cfg *config.Config) *AuthService {
 	return &AuthService{config: cfg}
 }
 
+// How long emailed verification and password reset links stay valid
+const (
+	verifyTokenTTL   = 48 * time.Hour
+	passwordResetTTL = 24 * time.Hour
+)
+
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
 // JWT Claims
 type Claims struct {
-	UserID uuid.UUID       `json:"user_id"`
-	Email  string          `json:"email"`
-	Role   models.UserRole `json:"role"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Email     string          `json:"email"`
+	Role      models.UserRole `json:"role"`
+	SessionID uuid.UUID       `json:"sid"`
+	TwoFactor bool            `json:"-"` // Whether the session passed 2FA, read from the session on validation
+
+	// Set on tokens an admin obtained to see the platform as the user. They
+	// are read-only unless ImpersonationWrites is set.
+	ImpersonatorID      *uuid.UUID `json:"imp,omitempty"`
+	ImpersonationWrites bool       `json:"imp_writes,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -42,14 +58,15 @@ func (s *AuthService) CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken creates a JWT token for a user
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(s.config.JWTExpiration) * time.Hour)
+// generateAccessToken creates a short-lived JWT for a user's session
+func (s *AuthService) generateAccessToken(user *models.User, sessionID uuid.UUID) (string, time.Time, error) {
+	expirationTime := time.Now().Add(time.Duration(s.config.AccessTokenTTL) * time.Minute)
 
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -57,11 +74,18 @@ func (s *AuthService) GenerateToken(user *models.User) (string, error) {
 		},
 	}
 
+	signed, err := s.signToken(claims)
+	return signed, expirationTime, err
+}
+
+// signToken signs claims into a JWT
+func (s *AuthService) signToken(claims *Claims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.config.JWTSecret))
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and the session it was issued for, and
+// returns the claims
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
@@ -80,6 +104,12 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	session, err := s.activeSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	claims.TwoFactor = session.TwoFactor
+
 	return claims, nil
 }
 
@@ -108,19 +138,12 @@ func (s *AuthService) Register(email, password, firstName, lastName string, role
 		return nil, err
 	}
 
-	// Generate verification token
-	verifyToken, err := s.GenerateRandomToken()
-	if err != nil {
-		return nil, err
-	}
-
 	user := &models.User{
 		Email:        email,
 		PasswordHash: passwordHash,
 		FirstName:    firstName,
 		LastName:     lastName,
 		Role:         role,
-		VerifyToken:  verifyToken,
 	}
 
 	if err := db.Create(user).Error; err != nil {
@@ -130,94 +153,99 @@ func (s *AuthService) Register(email, password, firstName, lastName string, role
 	return user, nil
 }
 
-// Login authenticates a user and returns a token
-func (s *AuthService) Login(email, password string) (*models.User, string, error) {
+// Authenticate checks a user's email and password. Callers sign the user in
+// with StartSession.
+func (s *AuthService) Authenticate(email, password string) (*models.User, error) {
 	db := database.GetDB()
 
 	var user models.User
 	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, errors.New("invalid credentials")
 	}
 
 	if !s.CheckPassword(password, user.PasswordHash) {
-		return nil, "", errors.New("invalid credentials")
+		return nil, errors.New("invalid credentials")
 	}
 
-	token, err := s.GenerateToken(&user)
-	if err != nil {
-		return nil, "", err
+	// Only told to someone who knows the password
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
 	}
 
-	return &user, token, nil
+	return &user, nil
 }
 
 // VerifyEmail verifies a user's email address
 func (s *AuthService) VerifyEmail(token string) error {
-	db := database.GetDB()
-
-	var user models.User
-	if err := db.Where("verify_token = ?", token).First(&user).Error; err != nil {
-		return errors.New("invalid verification token")
-	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeUserToken(tx, models.TokenPurposeVerifyEmail, token)
+		if errors.Is(err, errUserTokenExpired) {
+			return errors.New("verification token has expired")
+		}
+		if err != nil {
+			return errors.New("invalid verification token")
+		}
 
-	user.EmailVerified = true
-	user.VerifyToken = ""
-	return db.Save(&user).Error
+		return tx.Model(&models.User{}).Where("id = ?", record.UserID).Update("email_verified", true).Error
+	})
 }
 
-// InitiatePasswordReset creates a password reset token
-func (s *AuthService) InitiatePasswordReset(email string) (string, error) {
-	db := database.GetDB()
-
-	var user models.User
-	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
-		// Don't reveal if email exists
-		return "", nil
+// IssueVerifyToken creates a verification token for an unverified user,
+// replacing any earlier one
+func (s *AuthService) IssueVerifyToken(user *models.User) (string, error) {
+	if user.EmailVerified {
+		return "", ErrEmailAlreadyVerified
 	}
+	return s.issueUserToken(database.GetDB(), user.ID, models.TokenPurposeVerifyEmail, verifyTokenTTL, "")
+}
 
-	token, err := s.GenerateRandomToken()
+// InitiatePasswordReset creates a password reset token. Earlier reset links
+// stop working.
+func (s *AuthService) InitiatePasswordReset(email, ipAddress string) (*models.User, string, error) {
+	user, err := s.GetUserByEmail(email)
 	if err != nil {
-		return "", err
+		// Don't reveal if email exists
+		return nil, "", nil
 	}
 
-	expires := time.Now().Add(24 * time.Hour)
-	user.ResetToken = token
-	user.ResetExpires = &expires
-
-	if err := db.Save(&user).Error; err != nil {
-		return "", err
+	token, err := s.issueUserToken(database.GetDB(), user.ID, models.TokenPurposePasswordReset, passwordResetTTL, ipAddress)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return token, nil
+	return user, token, nil
 }
 
 // ResetPassword resets a user's password using a reset token
 func (s *AuthService) ResetPassword(token, newPassword string) error {
-	db := database.GetDB()
-
-	var user models.User
-	if err := db.Where("reset_token = ?", token).First(&user).Error; err != nil {
-		return errors.New("invalid reset token")
+	passwordHash, err := s.HashPassword(newPassword)
+	if err != nil {
+		return err
 	}
 
-	if user.ResetExpires == nil || time.Now().After(*user.ResetExpires) {
-		return errors.New("reset token has expired")
-	}
+	var userID uuid.UUID
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeUserToken(tx, models.TokenPurposePasswordReset, token)
+		if errors.Is(err, errUserTokenExpired) {
+			return errors.New("reset token has expired")
+		}
+		if err != nil {
+			return errors.New("invalid reset token")
+		}
 
-	passwordHash, err := s.HashPassword(newPassword)
+		userID = record.UserID
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", passwordHash).Error
+	})
 	if err != nil {
 		return err
 	}
 
-	user.PasswordHash = passwordHash
-	user.ResetToken = ""
-	user.ResetExpires = nil
-
-	return db.Save(&user).Error
+	// Whoever knew the old password is signed out everywhere
+	return s.RevokeAllSessions(userID, uuid.Nil)
 }
 
-// ChangePassword changes the user's password
-func (s *AuthService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error {
+// ChangePassword changes the user's password and ends their other sessions
+func (s *AuthService) ChangePassword(userID, sessionID uuid.UUID, currentPassword, newPassword string) error {
 	db := database.GetDB()
 
 	user, err := s.GetUserByID(userID)
@@ -237,7 +265,11 @@ func (s *AuthService) ChangePassword(userID uuid.UUID, currentPassword, newPassw
 	}
 
 	user.PasswordHash = string(hashedPassword)
-	return db.Save(user).Error
+	if err := db.Save(user).Error; err != nil {
+		return err
+	}
+
+	return s.RevokeAllSessions(userID, sessionID)
 }
 
 // GetUserByID retrieves a user by their ID
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCollaboratorNotFound     = errors.New("user is not a collaborator on this project")
+	ErrAlreadyCollaborator      = errors.New("user already collaborates on this project")
+	ErrInvalidProjectPermission = errors.New("permission must be editor or viewer")
+	ErrCollaboratorNotDeveloper = errors.New("only developers can collaborate on projects")
+	ErrProjectOwnerFixed        = errors.New("the project owner can only change by transferring ownership")
+	ErrNotProjectOwner          = errors.New("only the project owner can do this")
+	ErrAlreadyProjectOwner      = errors.New("user already owns this project")
+)
+
+// CollaboratorService manages the developers who work on a project together,
+// their invitations, and handing a project over to another of them
+type CollaboratorService struct {
+	authService *AuthService
+}
+
+func NewCollaboratorService(authService *AuthService) *CollaboratorService {
+	return &CollaboratorService{authService: authService}
+}
+
+// List returns a project's collaborators, owner first, and its pending
+// invitations
+func (s *CollaboratorService) List(projectID uuid.UUID) ([]models.ProjectCollaborator, []models.ProjectInvitation, error) {
+	db := database.GetDB()
+
+	var collaborators []models.ProjectCollaborator
+	if err := db.Preload("User").
+		Where("project_id = ?", projectID).
+		Order("CASE permission WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, created_at ASC").
+		Find(&collaborators).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var invitations []models.ProjectInvitation
+	if err := db.Where("project_id = ? AND accepted_at IS NULL AND expires_at > ?", projectID, time.Now()).
+		Order("created_at ASC").
+		Find(&invitations).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return collaborators, invitations, nil
+}
+
+// Invite emails an invitation to collaborate on the project. A new invitation
+// replaces any pending one to the same address. The returned token is the one
+// to put in the email.
+func (s *CollaboratorService) Invite(inviterID, projectID uuid.UUID, email string, permission models.ProjectPermission) (*models.ProjectInvitation, string, error) {
+	if !validCollaboratorPermission(permission) {
+		return nil, "", ErrInvalidProjectPermission
+	}
+
+	db := database.GetDB()
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	var existing models.User
+	if err := db.Where("LOWER(email) = ?", email).First(&existing).Error; err == nil {
+		if existing.Role != models.RoleDeveloper {
+			return nil, "", ErrCollaboratorNotDeveloper
+		}
+		var count int64
+		db.Model(&models.ProjectCollaborator{}).Where("project_id = ? AND user_id = ?", projectID, existing.ID).Count(&count)
+		if count > 0 {
+			return nil, "", ErrAlreadyCollaborator
+		}
+	}
+
+	token, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &models.ProjectInvitation{
+		ProjectID:   projectID,
+		Email:       email,
+		Permission:  permission,
+		TokenHash:   models.HashToken(token),
+		InvitedByID: inviterID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ? AND email = ? AND accepted_at IS NULL", projectID, email).
+			Delete(&models.ProjectInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// RevokeInvitation deletes a pending invitation
+func (s *CollaboratorService) RevokeInvitation(projectID, invitationID uuid.UUID) error {
+	result := database.GetDB().Where("id = ? AND project_id = ? AND accepted_at IS NULL", invitationID, projectID).
+		Delete(&models.ProjectInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes the user a collaborator on the project they were
+// invited to. As with organisation invitations, accepting proves the user
+// receives mail at the address, so it counts as verified afterwards.
+func (s *CollaboratorService) AcceptInvitation(userID uuid.UUID, token string) (*models.ProjectCollaborator, error) {
+	user, err := s.authService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.GetDB()
+
+	var invitation models.ProjectInvitation
+	if err := db.Preload("Project").First(&invitation, "token_hash = ?", models.HashToken(token)).Error; err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) || invitation.Project == nil {
+		return nil, ErrInvalidInvitation
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if user.Role != models.RoleDeveloper {
+		return nil, ErrCollaboratorNotDeveloper
+	}
+
+	collaborator := &models.ProjectCollaborator{
+		ProjectID:  invitation.ProjectID,
+		UserID:     userID,
+		Permission: invitation.Permission,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ProjectInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvitation
+		}
+
+		var existing int64
+		if err := tx.Model(&models.ProjectCollaborator{}).
+			Where("project_id = ? AND user_id = ?", invitation.ProjectID, userID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyCollaborator
+		}
+
+		if err := tx.Create(collaborator).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("email_verified", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	collaborator.Project = invitation.Project
+	return collaborator, nil
+}
+
+// UpdatePermission changes what a collaborator may do. The owner's permission
+// is not changed this way.
+func (s *CollaboratorService) UpdatePermission(projectID, userID uuid.UUID, permission models.ProjectPermission) (*models.ProjectCollaborator, error) {
+	if !validCollaboratorPermission(permission) {
+		return nil, ErrInvalidProjectPermission
+	}
+
+	db := database.GetDB()
+
+	var collaborator models.ProjectCollaborator
+	if err := db.First(&collaborator, "project_id = ? AND user_id = ?", projectID, userID).Error; err != nil {
+		return nil, ErrCollaboratorNotFound
+	}
+	if collaborator.Permission == models.ProjectPermissionOwner {
+		return nil, ErrProjectOwnerFixed
+	}
+
+	collaborator.Permission = permission
+	if err := db.Model(&collaborator).Update("permission", permission).Error; err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+// Remove takes a collaborator off the project. The owner cannot be removed
+// and must transfer ownership first.
+func (s *CollaboratorService) Remove(projectID, userID uuid.UUID) error {
+	result := database.GetDB().
+		Where("project_id = ? AND user_id = ? AND permission <> ?", projectID, userID, models.ProjectPermissionOwner).
+		Delete(&models.ProjectCollaborator{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var owner int64
+		database.GetDB().Model(&models.ProjectCollaborator{}).
+			Where("project_id = ? AND user_id = ?", projectID, userID).
+			Count(&owner)
+		if owner > 0 {
+			return ErrProjectOwnerFixed
+		}
+		return ErrCollaboratorNotFound
+	}
+	return nil
+}
+
+// TransferOwnership hands the project to another of its collaborators, who
+// becomes its developer. The previous owner stays on as an editor.
+func (s *CollaboratorService) TransferOwnership(ownerID, projectID, newOwnerID uuid.UUID, ipAddress string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var collaborator models.ProjectCollaborator
+		if err := tx.First(&collaborator, "project_id = ? AND user_id = ?", projectID, newOwnerID).Error; err != nil {
+			return ErrCollaboratorNotFound
+		}
+		if collaborator.Permission == models.ProjectPermissionOwner {
+			return ErrAlreadyProjectOwner
+		}
+
+		// The owner check and the handover happen in one step, so two
+		// transfers of the same project cannot both succeed
+		result := tx.Model(&models.Project{}).
+			Where("id = ? AND developer_id = ?", projectID, ownerID).
+			Update("developer_id", newOwnerID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotProjectOwner
+		}
+
+		if err := tx.Model(&models.ProjectCollaborator{}).
+			Where("project_id = ? AND user_id = ?", projectID, ownerID).
+			Update("permission", models.ProjectPermissionEditor).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&collaborator).Update("permission", models.ProjectPermissionOwner).Error; err != nil {
+			return err
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditProjectTransferred,
+			ActorID:   &ownerID,
+			UserID:    &newOwnerID,
+			IPAddress: ipAddress,
+			Details:   "project " + projectID.String(),
+		})
+		return nil
+	})
+}
+
+func validCollaboratorPermission(permission models.ProjectPermission) bool {
+	return permission == models.ProjectPermissionEditor || permission == models.ProjectPermissionViewer
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponExpired       = errors.New("coupon has expired")
+	ErrCouponExhausted     = errors.New("coupon has been fully redeemed")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this purchase")
+	ErrCouponAlreadyUsed   = errors.New("you have already used this coupon")
+)
+
+// findCoupon looks up an active coupon by code and checks the investor may
+// use it on the plan
+func (s *PaymentService) findCoupon(investorID uuid.UUID, plan *models.PricingPlan, code string) (*models.Coupon, error) {
+	db := database.GetDB()
+
+	var coupon models.Coupon
+	if err := db.Where("code = ? AND active = ?", models.NormalizeCouponCode(code), true).First(&coupon).Error; err != nil {
+		return nil, ErrCouponNotFound
+	}
+
+	if coupon.IsExpired() {
+		return nil, ErrCouponExpired
+	}
+	if coupon.IsExhausted() {
+		return nil, ErrCouponExhausted
+	}
+	if coupon.PlanID != nil && *coupon.PlanID != plan.ID {
+		return nil, ErrCouponNotApplicable
+	}
+	if coupon.UserID != nil && *coupon.UserID != investorID {
+		return nil, ErrCouponNotApplicable
+	}
+	if coupon.Type == models.CouponTypeFixed && coupon.Currency != plan.Currency {
+		return nil, ErrCouponNotApplicable
+	}
+
+	var used int64
+	db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND investor_id = ?", coupon.ID, investorID).Count(&used)
+	if used > 0 {
+		return nil, ErrCouponAlreadyUsed
+	}
+
+	return &coupon, nil
+}
+
+// redeemCoupon records the coupon used by a payment as it completes. Limits
+// were checked when the intent was created, but concurrent payments may have
+// used them up since. A payment the coupon waived entirely fails then; one
+// the investor has already paid for completes without a second redemption,
+// or with the coupon counted past its limit.
+func (s *PaymentService) redeemCoupon(tx *gorm.DB, payment *models.Payment) error {
+	if payment.CouponID == nil {
+		return nil
+	}
+	free := payment.Amount == 0
+
+	redemption := &models.CouponRedemption{
+		CouponID:   *payment.CouponID,
+		PaymentID:  payment.ID,
+		InvestorID: payment.InvestorID,
+		Discount:   payment.DiscountAmount,
+		Currency:   payment.Currency,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(redemption)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if free {
+			return ErrCouponAlreadyUsed
+		}
+		return nil
+	}
+
+	result = tx.Model(&models.Coupon{}).
+		Where("id = ? AND (max_redemptions = 0 OR redemptions < max_redemptions)", *payment.CouponID).
+		UpdateColumn("redemptions", gorm.Expr("redemptions + ?", 1))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	if free {
+		return ErrCouponExhausted
+	}
+
+	return tx.Model(&models.Coupon{}).
+		Where("id = ?", *payment.CouponID).
+		UpdateColumn("redemptions", gorm.Expr("redemptions + ?", 1)).Error
+}
+
+// GetCouponRedemptions returns the redemptions of a coupon, newest first
+func (s *PaymentService) GetCouponRedemptions(couponID uuid.UUID) ([]models.CouponRedemption, error) {
+	db := database.GetDB()
+
+	var redemptions []models.CouponRedemption
+	err := db.Where("coupon_id = ?", couponID).
+		Preload("Investor").
+		Order("created_at DESC").
+		Find(&redemptions).Error
+
+	return redemptions, err
+}
@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestFreeCouponLimits races investors for a coupon that waives the whole
+// fee. Only as many payments as the coupon allows may complete, and each
+// investor only once.
+func TestFreeCouponLimits(t *testing.T) {
+	cfg := testutil.Database(t)
+	paymentService := services.NewPaymentService(cfg)
+
+	coupon := &models.Coupon{
+		Code:           "FREE",
+		Type:           models.CouponTypePercent,
+		Value:          100,
+		MaxRedemptions: 2,
+		Active:         true,
+	}
+	if err := database.GetDB().Create(coupon).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Investors, one of whom tries twice at once
+	var investors []*models.User
+	for i := 0; i < 10; i++ {
+		investors = append(investors, testutil.User(t, models.RoleInvestor))
+	}
+	investors = append(investors, investors[0])
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, len(investors))
+	for i, investor := range investors {
+		wg.Add(1)
+		go func(i int, investor *models.User) {
+			defer wg.Done()
+			<-start
+			_, _, errs[i] = paymentService.CreatePaymentIntent(investor.ID, nil, "free")
+		}(i, investor)
+	}
+	close(start)
+	wg.Wait()
+
+	completed := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			completed++
+		case errors.Is(err, services.ErrCouponExhausted), errors.Is(err, services.ErrCouponAlreadyUsed):
+		case investors[i] == investors[0]:
+			// Whichever of the first investor's attempts loses may instead
+			// find the other's credits
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if completed != coupon.MaxRedemptions {
+		t.Errorf("%d free payments completed, want %d", completed, coupon.MaxRedemptions)
+	}
+
+	var stored models.Coupon
+	database.GetDB().First(&stored, "id = ?", coupon.ID)
+	if stored.Redemptions != coupon.MaxRedemptions {
+		t.Errorf("coupon redemptions = %d, want %d", stored.Redemptions, coupon.MaxRedemptions)
+	}
+
+	var redemptions, payments int64
+	database.GetDB().Model(&models.CouponRedemption{}).Where("coupon_id = ?", coupon.ID).Count(&redemptions)
+	database.GetDB().Model(&models.Payment{}).Where("coupon_id = ? AND status = ?", coupon.ID, models.PaymentStatusCompleted).Count(&payments)
+	if redemptions != int64(completed) || payments != int64(completed) {
+		t.Errorf("%d redemptions and %d completed payments, want %d", redemptions, payments, completed)
+	}
+}
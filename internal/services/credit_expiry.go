@@ -0,0 +1,141 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// creditExpiry returns when credits granted at the given time lapse, or nil
+// if they never do
+func creditExpiry(from time.Time, validityDays int) *time.Time {
+	if validityDays <= 0 {
+		return nil
+	}
+	expires := from.AddDate(0, 0, validityDays)
+	return &expires
+}
+
+// ExpireCredits voids the unused credits of payments whose validity window has
+// ended, recording an expiry entry in the ledger. It returns the number of
+// payments expired.
+func (s *PaymentService) ExpireCredits() (int, error) {
+	db := database.GetDB()
+	now := time.Now()
+
+	var payments []models.Payment
+	if err := db.Joins("JOIN (?) AS balances ON balances.payment_id = payments.id", creditBalances(db)).
+		Where("payments.status = ? AND payments.expires_at <= ?", models.PaymentStatusCompleted, now).
+		Where("balances.balance > 0").
+		Find(&payments).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range payments {
+		payment := &payments[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Take the same row lock as UseViewCredit and refunds so the
+			// balance cannot change while it is voided
+			result := tx.Model(&models.Payment{}).
+				Where("id = ? AND status = ?", payment.ID, models.PaymentStatusCompleted).
+				UpdateColumn("updated_at", now)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			balance, err := s.CreditBalance(tx, payment.ID)
+			if err != nil || balance <= 0 {
+				return err
+			}
+
+			expired++
+			return s.appendCredits(tx, payment, models.CreditEntryExpiry, -balance, nil, nil, "validity period ended")
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+
+	return expired, nil
+}
+
+// SendExpiryReminders emails investors whose unused credits lapse within the
+// configured reminder window. Each payment is reminded once.
+func (s *PaymentService) SendExpiryReminders(emailService *EmailService) (int, error) {
+	if s.config.ExpiryReminderDays <= 0 {
+		return 0, nil
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, s.config.ExpiryReminderDays)
+
+	var payments []models.Payment
+	if err := db.Joins("LEFT JOIN (?) AS balances ON balances.payment_id = payments.id", creditBalances(db)).
+		Where("payments.status = ? AND payments.reminder_sent_at IS NULL", models.PaymentStatusCompleted).
+		Where("payments.expires_at > ? AND payments.expires_at <= ?", now, cutoff).
+		Where("payments.unlimited = ? OR balances.balance > 0", true).
+		Preload("Investor").
+		Find(&payments).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range payments {
+		payment := &payments[i]
+
+		// Claim the reminder first so concurrent runs do not send it twice
+		result := db.Model(&models.Payment{}).
+			Where("id = ? AND reminder_sent_at IS NULL", payment.ID).
+			UpdateColumn("reminder_sent_at", now)
+		if result.Error != nil {
+			return sent, result.Error
+		}
+		if result.RowsAffected == 0 || payment.Investor == nil {
+			continue
+		}
+
+		balance, err := s.CreditBalance(db, payment.ID)
+		if err != nil {
+			return sent, err
+		}
+		payment.ProjectsRemaining = balance
+
+		if err := emailService.SendCreditExpiryReminder(payment.Investor, payment); err != nil {
+			// Release the claim so the next run tries again
+			db.Model(&models.Payment{}).Where("id = ?", payment.ID).UpdateColumn("reminder_sent_at", nil)
+			log.Printf("Failed to send credit expiry reminder for payment %s: %v", payment.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// RunCreditExpiryJob expires lapsed credits and sends expiry reminders now and
+// then every interval. It blocks, so run it in its own goroutine.
+func (s *PaymentService) RunCreditExpiryJob(emailService *EmailService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if expired, err := s.ExpireCredits(); err != nil {
+			log.Printf("Credit expiry job: failed to expire credits: %v", err)
+		} else if expired > 0 {
+			log.Printf("Credit expiry job: expired credits on %d payments", expired)
+		}
+
+		if sent, err := s.SendExpiryReminders(emailService); err != nil {
+			log.Printf("Credit expiry job: failed to send reminders: %v", err)
+		} else if sent > 0 {
+			log.Printf("Credit expiry job: sent %d reminders", sent)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// creditBalances returns a subquery of payment_id and balance for every payment with ledger entries
+func creditBalances(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&models.CreditLedgerEntry{}).
+		Select("payment_id, SUM(delta) AS balance").
+		Group("payment_id")
+}
+
+// CreditBalance returns the remaining view credits on a payment
+func (s *PaymentService) CreditBalance(tx *gorm.DB, paymentID uuid.UUID) (int, error) {
+	var balance int
+	err := tx.Model(&models.CreditLedgerEntry{}).
+		Where("payment_id = ?", paymentID).
+		Select("COALESCE(SUM(delta), 0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// loadCreditBalances fills ProjectsRemaining on each payment from the ledger
+func (s *PaymentService) loadCreditBalances(payments []models.Payment) error {
+	if len(payments) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(payments))
+	for i, p := range payments {
+		ids[i] = p.ID
+	}
+
+	var rows []struct {
+		PaymentID uuid.UUID
+		Balance   int
+	}
+	if err := creditBalances(database.GetDB()).Where("payment_id IN ?", ids).Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	balances := make(map[uuid.UUID]int, len(rows))
+	for _, r := range rows {
+		balances[r.PaymentID] = r.Balance
+	}
+	for i := range payments {
+		payments[i].ProjectsRemaining = balances[payments[i].ID]
+	}
+
+	return nil
+}
+
+// LoadCreditBalances fills ProjectsRemaining on payments loaded outside the service
+func (s *PaymentService) LoadCreditBalances(payments []models.Payment) error {
+	return s.loadCreditBalances(payments)
+}
+
+// appendCredits records a ledger entry for a payment
+func (s *PaymentService) appendCredits(tx *gorm.DB, payment *models.Payment, entryType models.CreditEntryType, delta int, projectID, actorID *uuid.UUID, reason string) error {
+	entry := &models.CreditLedgerEntry{
+		InvestorID: payment.InvestorID,
+		PaymentID:  payment.ID,
+		Type:       entryType,
+		Delta:      delta,
+		ProjectID:  projectID,
+		ActorID:    actorID,
+		Reason:     reason,
+	}
+	return tx.Create(entry).Error
+}
+
+// GrantCredits gives an investor complimentary view credits on behalf of an admin.
+// Grants are held against a zero-amount completed payment so they flow through
+// GetActivePayment like purchased credits.
+func (s *PaymentService) GrantCredits(adminID, investorID uuid.UUID, credits, validityDays int, reason string) (*models.Payment, error) {
+	if credits <= 0 {
+		return nil, errors.New("credits must be positive")
+	}
+
+	db := database.GetDB()
+
+	var investor models.User
+	if err := db.First(&investor, "id = ? AND role = ?", investorID, models.RoleInvestor).Error; err != nil {
+		return nil, errors.New("investor not found")
+	}
+
+	now := time.Now()
+	payment := &models.Payment{
+		InvestorID:    investorID,
+		Amount:        0,
+		Currency:      s.config.ViewFeeCurrency,
+		Status:        models.PaymentStatusCompleted,
+		ProjectsTotal: credits,
+		Description:   "Complimentary project views",
+		CompletedAt:   &now,
+	}
+	if validityDays == 0 {
+		validityDays = s.config.CreditValidityDays
+	}
+	payment.ExpiresAt = creditExpiry(now, validityDays)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		return s.appendCredits(tx, payment, models.CreditEntryAdminGrant, credits, nil, &adminID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payment.ProjectsRemaining = credits
+	return payment, nil
+}
+
+// GetCreditLedger returns an investor's ledger entries, and those of their
+// organisation's payments, newest first
+func (s *PaymentService) GetCreditLedger(investorID uuid.UUID) ([]models.CreditLedgerEntry, error) {
+	db := database.GetDB()
+
+	var entries []models.CreditLedgerEntry
+	err := ownedOrOrganizationPayments(db, investorID).
+		Preload("Project").
+		Order("created_at DESC").
+		Find(&entries).Error
+
+	return entries, err
+}
+
+// GetPaymentLedger returns the ledger entries of a single payment, oldest first
+func (s *PaymentService) GetPaymentLedger(paymentID uuid.UUID) ([]models.CreditLedgerEntry, error) {
+	db := database.GetDB()
+
+	var entries []models.CreditLedgerEntry
+	err := db.Where("payment_id = ?", paymentID).
+		Preload("Project").
+		Order("created_at ASC").
+		Find(&entries).Error
+
+	return entries, err
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
@@ -10,9 +11,16 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/ukuvago/angel-platform/internal/authz"
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTermSheetNotFound = errors.New("term sheet not found")
+	ErrNotTermSheetParty = errors.New("user not authorized to sign this term sheet")
 )
 
 type DocumentService struct {
@@ -75,6 +83,10 @@ The parties agree that electronic signatures shall be legally binding.`
 	pdf.Ln(5)
 	pdf.Cell(190, 5, "Email: "+investor.Email)
 	pdf.Ln(5)
+	if nda.Organization != nil {
+		pdf.Cell(190, 5, "On behalf of: "+nda.Organization.Name)
+		pdf.Ln(5)
+	}
 	pdf.Cell(190, 5, "Signed: "+nda.SignedAt.Format("January 2, 2006 15:04:05 MST"))
 	pdf.Ln(5)
 	pdf.Cell(190, 5, "IP Address: "+nda.IPAddress)
@@ -282,25 +294,26 @@ func (s *DocumentService) CreateTermSheet(offer *models.InvestmentOffer, project
 	return termSheet, nil
 }
 
-// SignTermSheet records a signature on a term sheet
-func (s *DocumentService) SignTermSheet(termSheetID uuid.UUID, userID uuid.UUID, signatureData, ipAddress string) (*models.TermSheet, error) {
+// SignTermSheet records a signature on a term sheet by whichever party the
+// user is to it
+func (s *DocumentService) SignTermSheet(termSheetID uuid.UUID, sub authz.Subject, signatureData, ipAddress string) (*models.TermSheet, error) {
 	db := database.GetDB()
 
 	var termSheet models.TermSheet
-	if err := db.Preload("Offer").First(&termSheet, "id = ?", termSheetID).Error; err != nil {
-		return nil, err
+	if err := db.Preload("Offer.Project.Collaborators").First(&termSheet, "id = ?", termSheetID).Error; err != nil {
+		return nil, ErrTermSheetNotFound
 	}
 
-	// Get the offer to determine user role
-	var offer models.InvestmentOffer
-	if err := db.Preload("Project").First(&offer, "id = ?", termSheet.OfferID).Error; err != nil {
-		return nil, err
+	res := authz.TermSheet(&termSheet)
+	if !authz.Can(sub, authz.ActionSign, res) {
+		return nil, ErrNotTermSheetParty
 	}
 
 	now := time.Now()
 
-	if offer.InvestorID == userID {
-		// Investor signing
+	switch authz.RelationOf(sub, res) {
+	case authz.Owner, authz.OwnerOrganization:
+		// Investor signing, for themselves or their firm
 		termSheet.InvestorSignature = signatureData
 		termSheet.InvestorSignedAt = &now
 		termSheet.InvestorIP = ipAddress
@@ -310,8 +323,8 @@ func (s *DocumentService) SignTermSheet(termSheetID uuid.UUID, userID uuid.UUID,
 		} else {
 			termSheet.Status = models.TermSheetStatusInvestorSigned
 		}
-	} else if offer.Project.DeveloperID == userID {
-		// Developer signing
+	case authz.Counterparty, authz.ProjectEditor, authz.CounterpartyOrganization:
+		// Developer signing, for themselves, a project they edit or their startup
 		termSheet.DeveloperSignature = signatureData
 		termSheet.DeveloperSignedAt = &now
 		termSheet.DeveloperIP = ipAddress
@@ -319,11 +332,9 @@ func (s *DocumentService) SignTermSheet(termSheetID uuid.UUID, userID uuid.UUID,
 		if termSheet.InvestorSignature != "" {
 			termSheet.Status = models.TermSheetStatusCompleted
 		}
-	} else {
-		return nil, fmt.Errorf("user not authorized to sign this term sheet")
 	}
 
-	if err := db.Save(&termSheet).Error; err != nil {
+	if err := db.Omit(clause.Associations).Save(&termSheet).Error; err != nil {
 		return nil, err
 	}
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/smtp"
+	"time"
 
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/models"
@@ -109,13 +110,13 @@ func (s *EmailService) renderEmail(data EmailData) (string, error) {
 }
 
 // SendVerificationEmail sends an email verification link
-func (s *EmailService) SendVerificationEmail(user *models.User) error {
+func (s *EmailService) SendVerificationEmail(user *models.User, token string) error {
 	data := EmailData{
 		UserName:    user.FirstName,
 		UserEmail:   user.Email,
 		Subject:     "Verify your email address",
 		Content:     template.HTML("<p>Thank you for registering with " + s.config.AppName + ". Please click the button below to verify your email address.</p>"),
-		ActionURL:   fmt.Sprintf("%s/verify-email?token=%s", s.config.AppURL, user.VerifyToken),
+		ActionURL:   fmt.Sprintf("%s/verify-email?token=%s", s.config.AppURL, token),
 		ActionLabel: "Verify Email",
 	}
 
@@ -146,6 +147,25 @@ func (s *EmailService) SendPasswordResetEmail(user *models.User, token string) e
 	return s.sendEmail(user.Email, data.Subject, body)
 }
 
+// SendMagicLinkEmail sends a passwordless sign-in link
+func (s *EmailService) SendMagicLinkEmail(user *models.User, token string) error {
+	data := EmailData{
+		UserName:    user.FirstName,
+		UserEmail:   user.Email,
+		Subject:     "Your sign-in link",
+		Content:     template.HTML("<p>Click the button below to sign in to " + s.config.AppName + ". This link will expire in 15 minutes and can only be used once. If you did not ask to sign in, you can ignore this email.</p>"),
+		ActionURL:   fmt.Sprintf("%s/magic-link?token=%s", s.config.AppURL, token),
+		ActionLabel: "Sign In",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(user.Email, data.Subject, body)
+}
+
 // SendOfferNotification notifies a developer of a new investment offer
 func (s *EmailService) SendOfferNotification(developer *models.User, investor *models.User, offer *models.InvestmentOffer, project *models.Project) error {
 	content := fmt.Sprintf(`
@@ -262,3 +282,178 @@ func (s *EmailService) SendTermSheetSignedNotification(recipient *models.User, p
 
 	return s.sendEmail(recipient.Email, data.Subject, body)
 }
+
+// SendCreditExpiryReminder warns an investor that their unused project views are about to lapse
+func (s *EmailService) SendCreditExpiryReminder(investor *models.User, payment *models.Payment) error {
+	remaining := "your unlimited project viewing access"
+	if !payment.Unlimited {
+		remaining = fmt.Sprintf("your %d remaining project views", payment.ProjectsRemaining)
+		if payment.ProjectsRemaining == 1 {
+			remaining = "your remaining project view"
+		}
+	}
+
+	content := fmt.Sprintf(`
+		<p>This is a reminder that %s will expire on <strong>%s</strong>.</p>
+		<p>Any views you have not used by then will lapse.</p>
+	`, remaining, payment.ExpiresAt.Format("January 2, 2006"))
+
+	data := EmailData{
+		UserName:    investor.FirstName,
+		UserEmail:   investor.Email,
+		Subject:     "Your project views are about to expire",
+		Content:     template.HTML(content),
+		ActionURL:   fmt.Sprintf("%s/projects", s.config.AppURL),
+		ActionLabel: "Browse Projects",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(investor.Email, data.Subject, body)
+}
+
+// SendAccountLockedNotification tells a user their account was locked after repeated failed sign-ins
+func (s *EmailService) SendAccountLockedNotification(user *models.User, ipAddress string, until time.Time) error {
+	content := fmt.Sprintf(`
+		<p>We locked your account after several failed attempts to sign in, most recently from IP address <strong>%s</strong>.</p>
+		<p>You can sign in again after <strong>%s</strong>. If this was not you, we recommend resetting your password.</p>
+	`, template.HTMLEscapeString(ipAddress), until.UTC().Format("January 2, 2006 15:04 MST"))
+
+	data := EmailData{
+		UserName:    user.FirstName,
+		UserEmail:   user.Email,
+		Subject:     "Your account has been temporarily locked",
+		Content:     template.HTML(content),
+		ActionURL:   fmt.Sprintf("%s/forgot-password", s.config.AppURL),
+		ActionLabel: "Reset Password",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(user.Email, data.Subject, body)
+}
+
+// SendImpersonationNotification tells a user an admin is viewing the platform as them
+func (s *EmailService) SendImpersonationNotification(user *models.User, admin *models.User, reason string, allowWrites bool, until time.Time) error {
+	access := "can only view your account"
+	if allowWrites {
+		access = "can view and make changes to your account, but cannot sign documents, pay or make or respond to offers"
+	}
+	content := fmt.Sprintf(`
+		<p>%s from our support team is viewing the platform as you to help with: <strong>%s</strong></p>
+		<p>They %s. Their access ends at <strong>%s</strong>, and everything they do is recorded.</p>
+		<p>If you did not ask for help, please reply to this email.</p>
+	`, template.HTMLEscapeString(admin.FullName()), template.HTMLEscapeString(reason), access, until.UTC().Format("January 2, 2006 15:04 MST"))
+
+	data := EmailData{
+		UserName:  user.FirstName,
+		UserEmail: user.Email,
+		Subject:   "Our support team is viewing your account",
+		Content:   template.HTML(content),
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(user.Email, data.Subject, body)
+}
+
+// SendKYCStatusNotification tells an investor their KYC submission was received, approved or rejected
+func (s *EmailService) SendKYCStatusNotification(investor *models.User, submission *models.KYCSubmission) error {
+	subject := "We received your verification documents"
+	content := `
+		<p>Thank you for submitting your identity and accreditation documents.</p>
+		<p>Our team will review them and let you know once they have been checked.</p>
+	`
+
+	switch submission.Status {
+	case models.KYCStatusApproved:
+		subject = "Your investor verification has been approved"
+		content = `
+		<p>Your identity and accreditation documents have been approved.</p>
+		<p>You can now make investment offers and sign term sheets.</p>
+	`
+	case models.KYCStatusRejected:
+		subject = "Your investor verification needs attention"
+		content = fmt.Sprintf(`
+		<p>We could not approve the documents you submitted for verification.</p>
+		<p><strong>Reason:</strong></p>
+		<p>%s</p>
+		<p>Please upload new documents from your profile.</p>
+	`, template.HTMLEscapeString(submission.RejectionReason))
+	}
+
+	data := EmailData{
+		UserName:    investor.FirstName,
+		UserEmail:   investor.Email,
+		Subject:     subject,
+		Content:     template.HTML(content),
+		ActionURL:   fmt.Sprintf("%s/profile", s.config.AppURL),
+		ActionLabel: "View Profile",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(investor.Email, data.Subject, body)
+}
+
+// SendOrganizationInvitation invites someone, who may not have an account yet, to join an organisation
+func (s *EmailService) SendOrganizationInvitation(email string, inviter *models.User, org *models.Organization, role models.OrganizationRole, token string) error {
+	content := fmt.Sprintf(`
+		<p><strong>%s %s</strong> has invited you to join <strong>%s</strong> on %s as a %s.</p>
+		<p>Sign in or create an account with this email address to accept. The invitation expires in 7 days.</p>
+	`, template.HTMLEscapeString(inviter.FirstName), template.HTMLEscapeString(inviter.LastName),
+		template.HTMLEscapeString(org.Name), s.config.AppName, role)
+
+	data := EmailData{
+		UserName:    "there",
+		UserEmail:   email,
+		Subject:     fmt.Sprintf("You're invited to join %s", org.Name),
+		Content:     template.HTML(content),
+		ActionURL:   fmt.Sprintf("%s/organization-invite?token=%s", s.config.AppURL, token),
+		ActionLabel: "Accept Invitation",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(email, data.Subject, body)
+}
+
+// SendProjectInvitation invites a developer, who may not have an account yet, to collaborate on a project
+func (s *EmailService) SendProjectInvitation(email string, inviter *models.User, project *models.Project, permission models.ProjectPermission, token string) error {
+	content := fmt.Sprintf(`
+		<p><strong>%s %s</strong> has invited you to collaborate on <strong>%s</strong> on %s with %s access.</p>
+		<p>Sign in or create a developer account with this email address to accept. The invitation expires in 7 days.</p>
+	`, template.HTMLEscapeString(inviter.FirstName), template.HTMLEscapeString(inviter.LastName),
+		template.HTMLEscapeString(project.Title), s.config.AppName, permission)
+
+	data := EmailData{
+		UserName:    "there",
+		UserEmail:   email,
+		Subject:     fmt.Sprintf("You're invited to collaborate on %s", project.Title),
+		Content:     template.HTML(content),
+		ActionURL:   fmt.Sprintf("%s/project-invite?token=%s", s.config.AppURL, token),
+		ActionLabel: "Accept Invitation",
+	}
+
+	body, err := s.renderEmail(data)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEmail(email, data.Subject, body)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// FakeProvider is a deterministic payment provider for tests and local
+// development. Intents stay pending until CompleteIntent or FailIntent is
+// called. Intents and refunds are kept in the database, so they outlive the
+// process and are shared by every instance.
+type FakeProvider struct {
+	webhookSecret string
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{webhookSecret: webhookSecret}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) SignatureHeader() string {
+	return "X-Fake-Signature"
+}
+
+func (p *FakeProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.FakeIntent{
+		ID:       "fake_pi_" + uuid.New().String(),
+		Status:   string(IntentStatusPending),
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Metadata: string(metadata),
+	}
+	if err := database.GetDB().Create(record).Error; err != nil {
+		return nil, err
+	}
+
+	intent := fakeIntent(record)
+	intent.ClientSecret = record.ID + "_secret"
+	return intent, nil
+}
+
+func (p *FakeProvider) GetIntent(intentID string) (*Intent, error) {
+	var record models.FakeIntent
+	if err := database.GetDB().First(&record, "id = ?", intentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIntentNotFound
+		}
+		return nil, err
+	}
+
+	return fakeIntent(&record), nil
+}
+
+// CompleteIntent simulates the customer paying an intent
+func (p *FakeProvider) CompleteIntent(intentID string) error {
+	return p.setStatus(intentID, IntentStatusSucceeded)
+}
+
+// FailIntent simulates the customer's payment being declined
+func (p *FakeProvider) FailIntent(intentID string) error {
+	return p.setStatus(intentID, IntentStatusFailed)
+}
+
+func (p *FakeProvider) setStatus(intentID string, status IntentStatus) error {
+	updates := map[string]interface{}{"status": string(status)}
+	if status == IntentStatusSucceeded {
+		updates["receipt_url"] = "https://example.com/receipts/" + intentID
+	}
+
+	result := database.GetDB().Model(&models.FakeIntent{}).
+		Where("id = ? AND status = ?", intentID, IntentStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		intent, err := p.GetIntent(intentID)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("intent %s is already %s", intentID, intent.Status)
+	}
+	return nil
+}
+
+func (p *FakeProvider) Refund(req RefundRequest) (string, error) {
+	var key *string
+	if req.IdempotencyKey != "" {
+		key = &req.IdempotencyKey
+	}
+
+	var id string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if key != nil {
+			var existing models.FakeRefund
+			err := tx.First(&existing, "idempotency_key = ?", *key).Error
+			if err == nil {
+				id = existing.ID
+				return nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		var intent models.FakeIntent
+		if err := tx.First(&intent, "id = ?", req.IntentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrIntentNotFound
+			}
+			return err
+		}
+		if intent.Status != string(IntentStatusSucceeded) {
+			return errors.New("only succeeded intents can be refunded")
+		}
+
+		refunded, err := fakeRefunded(tx, req.IntentID)
+		if err != nil {
+			return err
+		}
+		if req.Amount <= 0 || refunded+req.Amount > intent.Amount {
+			return errors.New("refund exceeds the amount paid")
+		}
+
+		refund := &models.FakeRefund{
+			ID:             "fake_re_" + uuid.New().String(),
+			IntentID:       req.IntentID,
+			Amount:         req.Amount,
+			IdempotencyKey: key,
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		id = refund.ID
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// RefundedAmount returns the total refunded on an intent
+func (p *FakeProvider) RefundedAmount(intentID string) int64 {
+	refunded, _ := fakeRefunded(database.GetDB(), intentID)
+	return refunded
+}
+
+func fakeRefunded(db *gorm.DB, intentID string) (int64, error) {
+	var refunded int64
+	err := db.Model(&models.FakeRefund{}).
+		Where("intent_id = ?", intentID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&refunded).Error
+	return refunded, err
+}
+
+func fakeIntent(record *models.FakeIntent) *Intent {
+	intent := &Intent{
+		ID:         record.ID,
+		Status:     IntentStatus(record.Status),
+		Amount:     record.Amount,
+		Currency:   record.Currency,
+		ReceiptURL: record.ReceiptURL,
+	}
+	if record.Metadata != "" {
+		json.Unmarshal([]byte(record.Metadata), &intent.Metadata)
+	}
+	return intent
+}
+
+// ParseWebhook accepts a JSON-encoded ProviderEvent signed with SignWebhook
+func (p *FakeProvider) ParseWebhook(payload []byte, signature string) (*ProviderEvent, error) {
+	if p.webhookSecret == "" {
+		return nil, ErrWebhookNotConfigured
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.sign(payload)) {
+		return nil, errors.New("invalid webhook signature")
+	}
+
+	var event ProviderEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// SignWebhook returns the signature ParseWebhook expects for a payload
+func (p *FakeProvider) SignWebhook(payload []byte) string {
+	return hex.EncodeToString(p.sign(payload))
+}
+
+func (p *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannotImpersonateAdmin = errors.New("admins cannot be impersonated")
+	ErrImpersonationReason    = errors.New("a reason for impersonating is required")
+)
+
+// Impersonation is a token that lets an admin see the platform as a user
+type Impersonation struct {
+	User        *models.User
+	AccessToken string
+	ExpiresAt   time.Time
+	AllowWrites bool
+}
+
+// Impersonate starts a session for the user on the admin's behalf and
+// returns an access token for it. The token is short-lived, cannot be
+// refreshed, and is read-only unless allowWrites is set.
+func (s *AccountService) Impersonate(adminID, userID uuid.UUID, reason string, allowWrites bool, userAgent, ipAddress string) (*Impersonation, error) {
+	if adminID == userID {
+		return nil, ErrCannotManageSelf
+	}
+	if reason == "" {
+		return nil, ErrImpersonationReason
+	}
+
+	user, err := s.authService.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Role == models.RoleAdmin {
+		return nil, ErrCannotImpersonateAdmin
+	}
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	// The session's refresh token is never handed out, so it cannot be renewed
+	unused, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(s.authService.config.ImpersonationTTL) * time.Minute)
+	session := &models.Session{
+		UserID:           userID,
+		RefreshTokenHash: models.HashToken(unused),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		ExpiresAt:        expiresAt,
+		LastUsedAt:       now,
+		ImpersonatorID:   &adminID,
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		details := "read-only: " + reason
+		if allowWrites {
+			details = "with writes: " + reason
+		}
+		recordAudit(tx, &models.AuditLog{
+			Action:    models.AuditImpersonationStarted,
+			ActorID:   &adminID,
+			UserID:    &userID,
+			IPAddress: ipAddress,
+			Details:   details,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.authService.signToken(&Claims{
+		UserID:              user.ID,
+		Email:               user.Email,
+		Role:                user.Role,
+		SessionID:           session.ID,
+		ImpersonatorID:      &adminID,
+		ImpersonationWrites: allowWrites,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    s.authService.config.AppName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Impersonation{
+		User:        user,
+		AccessToken: token,
+		ExpiresAt:   expiresAt,
+		AllowWrites: allowWrites,
+	}, nil
+}
+
+// AuditImpersonatedRequest records a request made with an impersonation token
+func (s *AuthService) AuditImpersonatedRequest(claims *Claims, method, path string, status int, ipAddress string) {
+	recordAudit(database.GetDB(), &models.AuditLog{
+		Action:    models.AuditImpersonatedRequest,
+		ActorID:   claims.ImpersonatorID,
+		UserID:    &claims.UserID,
+		IPAddress: ipAddress,
+		Details:   fmt.Sprintf("%s %s -> %d", method, path, status),
+	})
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvoiceUnavailable is returned for payments that have not been paid for
+var ErrInvoiceUnavailable = errors.New("no invoice is available for this payment")
+
+// IssueInvoice returns the invoice for a paid payment. Payments are invoiced
+// as they complete; older payments are numbered the first time their invoice
+// is asked for. Refunded payments keep their invoice.
+func (s *DocumentService) IssueInvoice(payment *models.Payment) (*models.Invoice, error) {
+	if payment.Status != models.PaymentStatusCompleted && payment.Status != models.PaymentStatusRefundPending &&
+		payment.Status != models.PaymentStatusRefunded {
+		return nil, ErrInvoiceUnavailable
+	}
+	// Admin grants are free and need no invoice
+	if payment.Amount == 0 {
+		return nil, ErrInvoiceUnavailable
+	}
+
+	return issueInvoice(database.GetDB(), s.config.VATRate, payment)
+}
+
+// issueInvoice returns the payment's invoice, numbering a new one if it has
+// none. Each attempt runs in a savepoint, so it can run inside the
+// transaction that completes the payment.
+func issueInvoice(tx *gorm.DB, vatRate float64, payment *models.Payment) (*models.Invoice, error) {
+	var investor models.User
+	if err := tx.Unscoped().First(&investor, "id = ?", payment.InvestorID).Error; err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		var invoice *models.Invoice
+		lastErr = tx.Transaction(func(tx *gorm.DB) error {
+			var existing models.Invoice
+			err := tx.First(&existing, "payment_id = ?", payment.ID).Error
+			if err == nil {
+				invoice = &existing
+				return nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			invoice = newInvoice(vatRate, payment, &investor)
+			var last sql.NullInt64
+			if err := tx.Model(&models.Invoice{}).Select("MAX(number)").Scan(&last).Error; err != nil {
+				return err
+			}
+			invoice.Number = last.Int64 + 1
+			return tx.Create(invoice).Error
+		})
+		if lastErr == nil {
+			invoice.Payment = payment
+			return invoice, nil
+		}
+		// A concurrent request took the number or invoiced this payment; look again
+	}
+
+	return nil, lastErr
+}
+
+// newInvoice builds an unnumbered invoice. View fees include VAT, so the VAT
+// is worked out backwards from the amount paid.
+func newInvoice(rate float64, payment *models.Payment, investor *models.User) *models.Invoice {
+	vat := int64(math.Round(float64(payment.Amount) * rate / (100 + rate)))
+
+	description := payment.Description
+	if description == "" {
+		description = fmt.Sprintf("Project view fee (%d projects)", payment.ProjectsTotal)
+	}
+
+	return &models.Invoice{
+		PaymentID:   payment.ID,
+		InvestorID:  payment.InvestorID,
+		BilledName:  investor.FullName(),
+		BilledEmail: investor.Email,
+		CompanyName: investor.CompanyName,
+		Description: description,
+		Currency:    payment.Currency,
+		Subtotal:    payment.Amount - vat,
+		VATRate:     rate,
+		VATAmount:   vat,
+		Total:       payment.Amount,
+		IssuedAt:    time.Now(),
+	}
+}
+
+// IssueOutstandingInvoices backfills invoices for paid payments that have
+// none, such as those completed before invoices were issued with the payment,
+// oldest payment first
+func (s *DocumentService) IssueOutstandingInvoices() error {
+	db := database.GetDB()
+
+	var payments []models.Payment
+	if err := db.Where("status IN ? AND amount > 0", []models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusRefundPending, models.PaymentStatusRefunded}).
+		Where("id NOT IN (?)", db.Model(&models.Invoice{}).Select("payment_id")).
+		Order("completed_at ASC").
+		Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for i := range payments {
+		if _, err := s.IssueInvoice(&payments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListInvoices returns invoices issued in [from, to), in number order. Nil
+// bounds are open.
+func (s *DocumentService) ListInvoices(from, to *time.Time) ([]models.Invoice, error) {
+	db := database.GetDB()
+
+	query := db.Preload("Payment").Order("number ASC")
+	if from != nil {
+		query = query.Where("issued_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("issued_at < ?", *to)
+	}
+
+	var invoices []models.Invoice
+	if err := query.Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// GenerateInvoicePDF generates a tax invoice PDF
+func (s *DocumentService) GenerateInvoicePDF(invoice *models.Invoice) (string, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	if err := s.renderInvoice(pdf, invoice); err != nil {
+		return "", err
+	}
+
+	// Save PDF
+	docsDir := filepath.Join(s.config.UploadDir, "documents", "invoices")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(docsDir, invoice.InvoiceNumber()+".pdf")
+
+	if err := pdf.OutputFileAndClose(filePath); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+func (s *DocumentService) renderInvoice(pdf *gofpdf.Fpdf, invoice *models.Invoice) error {
+	// Core fonts are cp1252; translate currency symbols and names
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	money := func(amount int64) string {
+		return tr(models.FormatCurrency(amount, invoice.Currency))
+	}
+
+	pdf.AddPage()
+
+	// Header
+	title := "INVOICE"
+	if invoice.VATRate > 0 {
+		title = "TAX INVOICE"
+	}
+	pdf.SetFont("Arial", "B", 20)
+	pdf.CellFormat(190, 12, title, "", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	// Issuer and invoice details
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(95, 6, tr(s.config.AppName))
+	pdf.SetFont("Arial", "", 10)
+	pdf.Cell(40, 6, "Invoice number:")
+	pdf.Cell(55, 6, invoice.InvoiceNumber())
+	pdf.Ln(6)
+
+	pdf.Cell(95, 6, tr(s.config.InvoiceAddress))
+	pdf.Cell(40, 6, "Invoice date:")
+	pdf.Cell(55, 6, invoice.IssuedAt.Format("January 2, 2006"))
+	pdf.Ln(6)
+
+	vatNumber := ""
+	if s.config.VATNumber != "" {
+		vatNumber = "VAT number: " + s.config.VATNumber
+	}
+	pdf.Cell(95, 6, vatNumber)
+	if invoice.Payment != nil && invoice.Payment.CompletedAt != nil {
+		pdf.Cell(40, 6, "Payment date:")
+		pdf.Cell(55, 6, invoice.Payment.CompletedAt.Format("January 2, 2006"))
+	}
+	pdf.Ln(12)
+
+	// Customer
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(190, 8, "BILL TO")
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 10)
+	if invoice.CompanyName != "" {
+		pdf.Cell(190, 5, tr(invoice.CompanyName))
+		pdf.Ln(5)
+	}
+	pdf.Cell(190, 5, tr(invoice.BilledName))
+	pdf.Ln(5)
+	pdf.Cell(190, 5, invoice.BilledEmail)
+	pdf.Ln(12)
+
+	// Line items
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(140, 8, "Description", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(50, 8, "Amount", "B", 1, "R", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(140, 8, tr(invoice.Description), "", 0, "L", false, 0, "")
+	pdf.CellFormat(50, 8, money(invoice.Subtotal), "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	// Totals
+	pdf.CellFormat(140, 6, "Subtotal (excl. VAT)", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(50, 6, money(invoice.Subtotal), "T", 1, "R", false, 0, "")
+	if invoice.VATRate > 0 {
+		pdf.CellFormat(140, 6, fmt.Sprintf("VAT (%g%%)", invoice.VATRate), "", 0, "R", false, 0, "")
+		pdf.CellFormat(50, 6, money(invoice.VATAmount), "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(140, 8, "Total ("+strings.ToUpper(invoice.Currency)+")", "", 0, "R", false, 0, "")
+	pdf.CellFormat(50, 8, money(invoice.Total), "", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.Cell(190, 6, "Paid in full. Thank you.")
+	pdf.Ln(6)
+	if invoice.Payment != nil && invoice.Payment.Status == models.PaymentStatusRefunded && invoice.Payment.RefundedAmount > 0 {
+		refunded := "Refunded " + money(invoice.Payment.RefundedAmount)
+		if invoice.Payment.RefundedAt != nil {
+			refunded += " on " + invoice.Payment.RefundedAt.Format("January 2, 2006")
+		}
+		pdf.Cell(190, 6, refunded+".")
+		pdf.Ln(6)
+	}
+	pdf.Ln(10)
+
+	// Footer
+	pdf.SetFont("Arial", "I", 8)
+	pdf.MultiCell(190, 4, tr("This invoice was generated via the "+s.config.AppName+" platform. Payment reference: "+invoice.PaymentID.String()), "", "", false)
+
+	return pdf.Error()
+}
+
+// WriteInvoicesCSV writes invoices as CSV for import into accounting software.
+// Amounts are in major currency units.
+func (s *DocumentService) WriteInvoicesCSV(w io.Writer, invoices []models.Invoice) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"invoice_number", "issued_at", "payment_id", "billed_name", "company_name", "email",
+		"description", "currency", "subtotal", "vat_rate", "vat", "total", "refunded",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, inv := range invoices {
+		var refunded int64
+		if inv.Payment != nil && inv.Payment.Status == models.PaymentStatusRefunded {
+			refunded = inv.Payment.RefundedAmount
+		}
+
+		record := []string{
+			inv.InvoiceNumber(),
+			inv.IssuedAt.Format(time.RFC3339),
+			inv.PaymentID.String(),
+			inv.BilledName,
+			inv.CompanyName,
+			inv.BilledEmail,
+			inv.Description,
+			strings.ToUpper(inv.Currency),
+			majorUnits(inv.Subtotal),
+			fmt.Sprintf("%g", inv.VATRate),
+			majorUnits(inv.VATAmount),
+			majorUnits(inv.Total),
+			majorUnits(refunded),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteInvoicesZip writes a zip archive with a PDF per invoice and the CSV summary
+func (s *DocumentService) WriteInvoicesZip(w io.Writer, invoices []models.Invoice) error {
+	archive := zip.NewWriter(w)
+
+	for i := range invoices {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		if err := s.renderInvoice(pdf, &invoices[i]); err != nil {
+			return err
+		}
+
+		f, err := archive.Create(invoices[i].InvoiceNumber() + ".pdf")
+		if err != nil {
+			return err
+		}
+		if err := pdf.Output(f); err != nil {
+			return err
+		}
+	}
+
+	f, err := archive.Create("invoices.csv")
+	if err != nil {
+		return err
+	}
+	if err := s.WriteInvoicesCSV(f, invoices); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func majorUnits(cents int64) string {
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
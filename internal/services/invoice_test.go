@@ -0,0 +1,50 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestInvoicedOnCompletion checks paid payments are numbered as they
+// complete, and free ones are not invoiced
+func TestInvoicedOnCompletion(t *testing.T) {
+	cfg := testutil.Database(t)
+	fake := services.NewFakeProvider("whsec_test")
+	paymentService := services.NewPaymentServiceWithProvider(cfg, fake)
+
+	var payments []*models.Payment
+	for i := 0; i < 3; i++ {
+		payments = append(payments, paidPayment(t, paymentService, fake, testutil.User(t, models.RoleInvestor)))
+	}
+	admin := testutil.User(t, models.RoleAdmin)
+	grant, err := paymentService.GrantCredits(admin.ID, testutil.User(t, models.RoleInvestor).ID, 2, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, payment := range payments {
+		var invoice models.Invoice
+		if err := database.GetDB().First(&invoice, "payment_id = ?", payment.ID).Error; err != nil {
+			t.Fatalf("payment %d was not invoiced: %v", i, err)
+		}
+		if invoice.Number != int64(i+1) || invoice.Total != payment.Amount {
+			t.Errorf("payment %d: invoice %d for %d, want %d for %d", i, invoice.Number, invoice.Total, i+1, payment.Amount)
+		}
+	}
+
+	var invoices int64
+	database.GetDB().Model(&models.Invoice{}).Where("payment_id = ?", grant.ID).Count(&invoices)
+	if invoices != 0 {
+		t.Error("an admin grant was invoiced")
+	}
+
+	// Asking for an invoice later returns the one already issued
+	invoice, err := services.NewDocumentService(cfg).IssueInvoice(loadPayment(t, payments[1].ID))
+	if err != nil || invoice.Number != 2 {
+		t.Errorf("issue invoice: %v, number %v", err, invoice)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKYCSubmissionNotFound   = errors.New("KYC submission not found")
+	ErrKYCDocumentNotFound     = errors.New("KYC document not found")
+	ErrKYCAlreadyApproved      = errors.New("investor is already verified")
+	ErrKYCPending              = errors.New("a KYC submission is already awaiting review")
+	ErrKYCDocumentsRequired    = errors.New("an identity document and proof of accreditation are required")
+	ErrKYCNotPending           = errors.New("KYC submission is not awaiting review")
+	ErrRejectionReasonRequired = errors.New("rejection reason is required")
+)
+
+// KYCService keeps investors' identity and accreditation submissions and
+// their review by admins
+type KYCService struct {
+	storageService *StorageService
+}
+
+func NewKYCService(storageService *StorageService) *KYCService {
+	return &KYCService{storageService: storageService}
+}
+
+// Submit stores an investor's documents as a new submission awaiting review.
+// At least one document of every type is required.
+func (s *KYCService) Submit(investorID uuid.UUID, files map[models.KYCDocumentType][]*multipart.FileHeader) (*models.KYCSubmission, error) {
+	if len(files[models.KYCDocumentIdentity]) == 0 || len(files[models.KYCDocumentAccreditation]) == 0 {
+		return nil, ErrKYCDocumentsRequired
+	}
+
+	db := database.GetDB()
+
+	var investor models.User
+	if err := db.First(&investor, "id = ?", investorID).Error; err != nil {
+		return nil, err
+	}
+	switch investor.KYCStatus {
+	case models.KYCStatusApproved:
+		return nil, ErrKYCAlreadyApproved
+	case models.KYCStatusPending:
+		return nil, ErrKYCPending
+	}
+
+	submission := &models.KYCSubmission{
+		ID:         uuid.New(),
+		InvestorID: investorID,
+		Status:     models.KYCStatusPending,
+	}
+
+	for docType, headers := range files {
+		for _, file := range headers {
+			path, err := s.storageService.SaveKYCDocument(submission.ID, file)
+			if err != nil {
+				s.storageService.DeleteKYCSubmission(submission.ID)
+				return nil, err
+			}
+			submission.Documents = append(submission.Documents, models.KYCDocument{
+				Type:     docType,
+				FilePath: path,
+				FileName: file.Filename,
+			})
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The status check above may have raced with another submission
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND kyc_status IN ?", investorID, []models.KYCStatus{models.KYCStatusNone, models.KYCStatusRejected}).
+			Update("kyc_status", models.KYCStatusPending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrKYCPending
+		}
+
+		return tx.Create(submission).Error
+	})
+	if err != nil {
+		s.storageService.DeleteKYCSubmission(submission.ID)
+		return nil, err
+	}
+
+	investor.KYCStatus = models.KYCStatusPending
+	submission.Investor = &investor
+	return submission, nil
+}
+
+// LatestSubmission returns an investor's most recent submission
+func (s *KYCService) LatestSubmission(investorID uuid.UUID) (*models.KYCSubmission, error) {
+	var submission models.KYCSubmission
+	err := database.GetDB().Preload("Documents").
+		Where("investor_id = ?", investorID).
+		Order("created_at DESC").
+		First(&submission).Error
+	if err != nil {
+		return nil, ErrKYCSubmissionNotFound
+	}
+	return &submission, nil
+}
+
+// GetSubmission returns a submission with its investor and documents
+func (s *KYCService) GetSubmission(id uuid.UUID) (*models.KYCSubmission, error) {
+	var submission models.KYCSubmission
+	if err := database.GetDB().Preload("Investor").Preload("Documents").First(&submission, "id = ?", id).Error; err != nil {
+		return nil, ErrKYCSubmissionNotFound
+	}
+	return &submission, nil
+}
+
+// ListSubmissions returns submissions with a status, oldest first so the
+// review queue is worked in order. An empty status lists all of them.
+func (s *KYCService) ListSubmissions(status models.KYCStatus) ([]models.KYCSubmission, error) {
+	query := database.GetDB().Preload("Investor").Preload("Documents").Order("created_at ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var submissions []models.KYCSubmission
+	if err := query.Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+	return submissions, nil
+}
+
+// GetDocument returns a document with the submission it belongs to. The
+// files of deleted accounts are gone.
+func (s *KYCService) GetDocument(id uuid.UUID) (*models.KYCDocument, error) {
+	var document models.KYCDocument
+	if err := database.GetDB().Preload("Submission").First(&document, "id = ?", id).Error; err != nil || document.FilePath == "" {
+		return nil, ErrKYCDocumentNotFound
+	}
+	return &document, nil
+}
+
+// DocumentPath returns where a document's file is stored
+func (s *KYCService) DocumentPath(document *models.KYCDocument) string {
+	return s.storageService.GetKYCDocumentPath(document.FilePath)
+}
+
+// Review approves or rejects a pending submission on behalf of an admin and
+// updates the investor's status to match. Rejections need a reason, which is
+// shown to the investor.
+func (s *KYCService) Review(adminID, submissionID uuid.UUID, approve bool, reason, ipAddress string) (*models.KYCSubmission, error) {
+	if !approve && reason == "" {
+		return nil, ErrRejectionReasonRequired
+	}
+
+	submission, err := s.GetSubmission(submissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, action := models.KYCStatusApproved, models.AuditKYCApproved
+	if !approve {
+		status, action = models.KYCStatusRejected, models.AuditKYCRejected
+	} else {
+		reason = ""
+	}
+	now := time.Now()
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.KYCSubmission{}).
+			Where("id = ? AND status = ?", submissionID, models.KYCStatusPending).
+			Updates(map[string]interface{}{
+				"status":           status,
+				"rejection_reason": reason,
+				"reviewed_by_id":   adminID,
+				"reviewed_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrKYCNotPending
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", submission.InvestorID).Update("kyc_status", status).Error; err != nil {
+			return err
+		}
+
+		recordAudit(tx, &models.AuditLog{
+			Action:    action,
+			ActorID:   &adminID,
+			UserID:    &submission.InvestorID,
+			IPAddress: ipAddress,
+			Details:   reason,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	submission.Status = status
+	submission.RejectionReason = reason
+	submission.ReviewedByID = &adminID
+	submission.ReviewedAt = &now
+	if submission.Investor != nil {
+		submission.Investor.KYCStatus = status
+	}
+	return submission, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+)
+
+const (
+	attemptWindow = time.Hour // failures older than this no longer count
+	backoffAfter  = 3         // failures allowed before each further attempt is delayed
+)
+
+// ErrTooManyAttempts is returned while an account, IP address or action is throttled
+var ErrTooManyAttempts = errors.New("too many attempts, please try again later")
+
+// LoginLimiter slows down password guessing. Each failure delays the next
+// attempt on the account exponentially, and too many failures lock the
+// account or block the IP address for a while.
+type LoginLimiter struct {
+	config       *config.Config
+	store        AttemptStore
+	emailService *EmailService
+}
+
+func NewLoginLimiter(cfg *config.Config, emailService *EmailService) *LoginLimiter {
+	var store AttemptStore = NewDatabaseAttemptStore()
+	if cfg.LoginLimiter == "memory" {
+		store = NewMemoryAttemptStore()
+	}
+
+	return &LoginLimiter{
+		config:       cfg,
+		store:        store,
+		emailService: emailService,
+	}
+}
+
+// Check returns how long the caller must wait before trying the account from
+// this IP address again, zero if they may try now
+func (l *LoginLimiter) Check(email, ipAddress string) time.Duration {
+	return l.wait(accountKey(email), ipKey(ipAddress))
+}
+
+// Failure records a failed sign-in. The account is locked, with an audit
+// entry and an email to its owner, when it reaches the failure limit.
+func (l *LoginLimiter) Failure(email, ipAddress string) {
+	now := time.Now()
+	since := now.Add(-attemptWindow)
+
+	if failures, err := l.store.Fail(ipKey(ipAddress), now, since); err != nil {
+		log.Printf("Login limiter: %v", err)
+	} else if failures >= l.config.LoginMaxIPFailures {
+		l.store.Block(ipKey(ipAddress), now.Add(l.lockout()))
+	}
+
+	key := accountKey(email)
+	failures, err := l.store.Fail(key, now, since)
+	if err != nil {
+		log.Printf("Login limiter: %v", err)
+		return
+	}
+
+	switch {
+	case failures == l.config.LoginMaxFailures:
+		until := now.Add(l.lockout())
+		l.store.Block(key, until)
+		l.lockedOut(email, ipAddress, failures, until)
+	case failures > backoffAfter:
+		// 1s, 2s, 4s, ... but never longer than a lockout
+		delay := time.Duration(math.Pow(2, float64(failures-backoffAfter-1))) * time.Second
+		if delay > l.lockout() {
+			delay = l.lockout()
+		}
+		l.store.Block(key, now.Add(delay))
+	}
+}
+
+// Success forgets the account's failures after a correct password
+func (l *LoginLimiter) Success(email string) {
+	if err := l.store.Reset(accountKey(email)); err != nil {
+		log.Printf("Login limiter: %v", err)
+	}
+}
+
+// Throttle counts a request for a rate-limited action, such as sending a
+// password reset email, against key. It returns how long to wait once the key
+// has been used more than max times within the window.
+func (l *LoginLimiter) Throttle(max int, key string) time.Duration {
+	if wait := l.wait(key); wait > 0 {
+		return wait
+	}
+
+	now := time.Now()
+	count, err := l.store.Fail(key, now, now.Add(-attemptWindow))
+	if err != nil {
+		log.Printf("Login limiter: %v", err)
+		return 0
+	}
+	if count > max {
+		l.store.Block(key, now.Add(attemptWindow))
+		return attemptWindow
+	}
+	return 0
+}
+
+// Unlock lifts an account lockout on behalf of an admin
+func (l *LoginLimiter) Unlock(adminID uuid.UUID, user *models.User, ipAddress string) error {
+	if err := l.store.Reset(accountKey(user.Email)); err != nil {
+		return err
+	}
+
+	recordAudit(database.GetDB(), &models.AuditLog{
+		Action:    models.AuditAccountUnlocked,
+		ActorID:   &adminID,
+		UserID:    &user.ID,
+		IPAddress: ipAddress,
+	})
+	return nil
+}
+
+// wait returns the longest remaining block among the keys
+func (l *LoginLimiter) wait(keys ...string) time.Duration {
+	var longest time.Duration
+	for _, key := range keys {
+		until, err := l.store.BlockedUntil(key)
+		if err != nil {
+			log.Printf("Login limiter: %v", err)
+			continue
+		}
+		if wait := time.Until(until); wait > longest {
+			longest = wait
+		}
+	}
+	return longest
+}
+
+// lockedOut audits a lockout and tells the account owner. Unknown emails are
+// throttled the same way, so lockouts do not reveal which accounts exist.
+func (l *LoginLimiter) lockedOut(email, ipAddress string, failures int, until time.Time) {
+	db := database.GetDB()
+
+	var user models.User
+	if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+		return
+	}
+
+	recordAudit(db, &models.AuditLog{
+		Action:    models.AuditAccountLocked,
+		UserID:    &user.ID,
+		IPAddress: ipAddress,
+		Details:   fmt.Sprintf("%d failed sign-in attempts; locked until %s", failures, until.UTC().Format(time.RFC3339)),
+	})
+
+	go l.emailService.SendAccountLockedNotification(&user, ipAddress, until)
+}
+
+func (l *LoginLimiter) lockout() time.Duration {
+	return time.Duration(l.config.LoginLockoutMinutes) * time.Minute
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownOIDCProvider  = errors.New("unknown sign-in provider")
+	ErrInvalidOIDCState     = errors.New("sign-in expired or was not started here, please try again")
+	ErrInvalidIDToken       = errors.New("identity provider returned an invalid ID token")
+	ErrOIDCEmailNotVerified = errors.New("the identity provider has not verified this email address")
+	ErrOIDCRoleRequired     = errors.New("no account uses this email yet, sign up and choose a role first")
+)
+
+// oidcLoginTTL is how long a user has to finish signing in at the provider
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDCService signs users in through OpenID Connect providers with the
+// authorization code flow and PKCE
+type OIDCService struct {
+	config      *config.Config
+	authService *AuthService
+	client      *http.Client
+
+	mu        sync.Mutex
+	discovery map[string]*oidcDiscovery
+	keys      map[string]map[string]crypto.PublicKey // Signing keys by provider and key ID
+}
+
+func NewOIDCService(cfg *config.Config, authService *AuthService) *OIDCService {
+	return &OIDCService{
+		config:      cfg,
+		authService: authService,
+		client:      &http.Client{Timeout: 15 * time.Second},
+		discovery:   make(map[string]*oidcDiscovery),
+		keys:        make(map[string]map[string]crypto.PublicKey),
+	}
+}
+
+// oidcDiscovery is the subset of a provider's discovery document we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims are the ID token claims we read
+type oidcClaims struct {
+	Nonce           string      `json:"nonce"`
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"` // Some providers send "true" as a string
+	GivenName       string      `json:"given_name"`
+	FamilyName      string      `json:"family_name"`
+	Name            string      `json:"name"`
+	AuthorizedParty string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+func (c *oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Providers returns the names of the configured providers
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.config.OIDCProviders))
+	for name := range s.config.OIDCProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartLogin records a new sign-in and returns the provider URL to send the
+// browser to, and the state the callback must carry. role is the role of the
+// account to create if none matches; leave it empty to only sign in.
+func (s *OIDCService) StartLogin(providerName string, role models.UserRole) (authURL, state string, err error) {
+	provider, ok := s.config.OIDCProviders[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+	if role != "" && role != models.RoleInvestor && role != models.RoleDeveloper {
+		return "", "", errors.New("role must be investor or developer")
+	}
+
+	discovery, err := s.discover(provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = s.authService.GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	login := &models.OIDCLogin{
+		StateHash:    models.HashToken(state),
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Role:         role,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}
+	db := database.GetDB()
+	if err := db.Create(login).Error; err != nil {
+		return "", "", err
+	}
+
+	// Sign-ins that were abandoned at the provider are never completed
+	db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLogin{})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {s.redirectURI(provider)},
+		"scope":                 {strings.Join(provider.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), state, nil
+}
+
+// CompleteLogin exchanges the code the provider redirected back with for an
+// ID token, validates it, and returns the user it belongs to. Users are
+// matched by their linked identity, then by verified email; failing both a
+// new account is created with the role chosen when the sign-in started.
+func (s *OIDCService) CompleteLogin(providerName, state, code string) (*models.User, error) {
+	provider, ok := s.config.OIDCProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	login, err := s.consumeLogin(provider.Name, state)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery, err := s.discover(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(provider, discovery, code, login.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.validateIDToken(provider, discovery, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.linkUser(provider, claims, login.Role)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+	return user, nil
+}
+
+// consumeLogin looks up and deletes a pending sign-in, so each state works once
+func (s *OIDCService) consumeLogin(providerName, state string) (*models.OIDCLogin, error) {
+	if state == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	db := database.GetDB()
+	var login models.OIDCLogin
+	if err := db.First(&login, "state_hash = ?", models.HashToken(state)).Error; err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+
+	result := db.Where("state_hash = ?", login.StateHash).Delete(&models.OIDCLogin{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || login.Provider != providerName || time.Now().After(login.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	return &login, nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint
+func (s *OIDCService) exchangeCode(provider config.OIDCProvider, discovery *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURI(provider)},
+		"client_id":     {provider.ClientID},
+		"client_secret": {provider.ClientSecret},
+		"code_verifier": {verifier},
+	}
+
+	resp, err := s.client.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: unreadable token response (HTTP %d)", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 || body.Error != "" {
+		return "", fmt.Errorf("oidc: code exchange failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", ErrInvalidIDToken
+	}
+
+	return body.IDToken, nil
+}
+
+// validateIDToken checks the ID token's signature against the provider's
+// published keys, its issuer, audience, expiry and nonce
+func (s *OIDCService) validateIDToken(provider config.OIDCProvider, discovery *oidcDiscovery, rawIDToken, nonce string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.signingKey(provider, discovery, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	// A token issued to several clients must name us as the one it is for
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != provider.ClientID {
+		return nil, ErrInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// linkUser returns the user an identity belongs to, linking or creating one
+// on first sign-in
+func (s *OIDCService) linkUser(provider config.OIDCProvider, claims *oidcClaims, role models.UserRole) (*models.User, error) {
+	db := database.GetDB()
+	now := time.Now()
+
+	var identity models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity).Error
+	if err == nil {
+		db.Model(&identity).Update("last_login_at", now)
+		return s.authService.GetUserByID(identity.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Without a verified email anyone could claim an existing account
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.emailVerified() {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+		switch {
+		case err == nil:
+			if !user.EmailVerified {
+				user.EmailVerified = true
+				if err := tx.Model(&user).Update("email_verified", true).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if role == "" {
+				return ErrOIDCRoleRequired
+			}
+			if err := s.createUser(tx, &user, email, role, claims); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider.Name,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// createUser creates an account for a new user. It gets a random password
+// they can replace through password reset.
+func (s *OIDCService) createUser(tx *gorm.DB, user *models.User, email string, role models.UserRole, claims *oidcClaims) error {
+	password, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+	passwordHash, err := s.authService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+
+	*user = models.User{
+		Email:         email,
+		PasswordHash:  passwordHash,
+		Role:          role,
+		FirstName:     firstName,
+		LastName:      lastName,
+		EmailVerified: true,
+	}
+	return tx.Create(user).Error
+}
+
+func (s *OIDCService) redirectURI(provider config.OIDCProvider) string {
+	return fmt.Sprintf("%s/api/auth/oidc/%s/callback", s.config.AppURL, provider.Name)
+}
+
+// discover fetches and caches a provider's discovery document
+func (s *OIDCService) discover(provider config.OIDCProvider) (*oidcDiscovery, error) {
+	s.mu.Lock()
+	cached := s.discovery[provider.Name]
+	s.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := s.getJSON(provider.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != provider.Issuer {
+		return nil, fmt.Errorf("oidc: %s discovery names issuer %q", provider.Name, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: %s discovery document is incomplete", provider.Name)
+	}
+
+	s.mu.Lock()
+	s.discovery[provider.Name] = &discovery
+	s.mu.Unlock()
+	return &discovery, nil
+}
+
+// signingKey returns the provider key with the given ID. The key set is
+// fetched again when the ID is unknown, as providers rotate their keys.
+func (s *OIDCService) signingKey(provider config.OIDCProvider, discovery *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[provider.Name][kid]
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := s.fetchKeys(discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[provider.Name] = keys
+	s.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Providers with a single key may leave the key ID out of tokens
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, errors.New("oidc: unknown signing key")
+}
+
+// fetchKeys reads the RSA and EC signing keys from a JSON Web Key Set
+func (s *OIDCService) fetchKeys(jwksURI string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(jwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *OIDCService) getJSON(endpoint string, out interface{}) error {
+	resp, err := s.client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s returned HTTP %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
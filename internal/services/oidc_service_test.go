@@ -0,0 +1,255 @@
+package services_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+const (
+	oidcClientID     = "angel-platform"
+	oidcClientSecret = "client-secret"
+)
+
+// oidcGrant is an authorization code the mock issuer has handed out
+type oidcGrant struct {
+	nonce     string
+	challenge string
+	claims    jwt.MapClaims
+	signer    *rsa.PrivateKey
+}
+
+// oidcIssuer is a mock OpenID provider serving discovery, a key set and a
+// token endpoint that enforces PKCE
+type oidcIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*oidcGrant
+}
+
+func newOIDCIssuer(t *testing.T) *oidcIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &oidcIssuer{key: key, grants: map[string]*oidcGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *oidcIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 i.URL,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *oidcIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *oidcIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	i.mu.Lock()
+	grant, ok := i.grants[r.Form.Get("code")]
+	delete(i.grants, r.Form.Get("code"))
+	i.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge ||
+		r.Form.Get("client_id") != oidcClientID || r.Form.Get("client_secret") != oidcClientSecret {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   i.URL,
+		"aud":   oidcClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	idToken, _ := token.SignedString(grant.signer)
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "access_token": "access"})
+}
+
+// authorize plays the user approving the sign-in at the issuer, returning
+// the code it would redirect back with
+func (i *oidcIssuer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (string, *oidcGrant) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("sign-in started without PKCE: %s", authURL)
+	}
+
+	code := uuid.NewString()
+	grant := &oidcGrant{
+		nonce:     query.Get("nonce"),
+		challenge: query.Get("code_challenge"),
+		claims:    claims,
+		signer:    i.key,
+	}
+	i.mu.Lock()
+	i.grants[code] = grant
+	i.mu.Unlock()
+	return code, grant
+}
+
+func oidcUser(email string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":            uuid.NewString(),
+		"email":          email,
+		"email_verified": true,
+		"given_name":     "Thandi",
+		"family_name":    "Mokoena",
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newOIDCIssuer(t)
+	cfg := testutil.Database(t)
+	cfg.OIDCProviders = map[string]config.OIDCProvider{
+		"mock": {Name: "mock", Issuer: issuer.URL, ClientID: oidcClientID, ClientSecret: oidcClientSecret, Scopes: []string{"openid", "email"}},
+	}
+	oidcService := services.NewOIDCService(cfg, services.NewAuthService(cfg))
+
+	// start begins a sign-in that creates an investor, and has the issuer approve it
+	start := func(t *testing.T, claims jwt.MapClaims) (state, code string, grant *oidcGrant) {
+		t.Helper()
+		authURL, state, err := oidcService.StartLogin("mock", models.RoleInvestor)
+		if err != nil {
+			t.Fatalf("start login: %v", err)
+		}
+		code, grant = issuer.authorize(t, authURL, claims)
+		return state, code, grant
+	}
+
+	t.Run("signs in and links the identity", func(t *testing.T) {
+		claims := oidcUser("thandi@example.com")
+		state, code, _ := start(t, claims)
+		user, err := oidcService.CompleteLogin("mock", state, code)
+		if err != nil {
+			t.Fatalf("complete login: %v", err)
+		}
+		if user.Role != models.RoleInvestor || !user.EmailVerified || user.FirstName != "Thandi" {
+			t.Errorf("created user: %+v", user)
+		}
+
+		state, code, _ = start(t, claims)
+		again, err := oidcService.CompleteLogin("mock", state, code)
+		if err != nil || again.ID != user.ID {
+			t.Fatalf("second sign-in: %v, user %v want %v", err, again, user.ID)
+		}
+		var identities int64
+		database.GetDB().Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&identities)
+		if identities != 1 {
+			t.Errorf("%d identities linked, want 1", identities)
+		}
+	})
+
+	t.Run("state works once", func(t *testing.T) {
+		state, code, _ := start(t, oidcUser("state@example.com"))
+		if _, err := oidcService.CompleteLogin("mock", "not-"+state, code); !errors.Is(err, services.ErrInvalidOIDCState) {
+			t.Errorf("unknown state: %v, want ErrInvalidOIDCState", err)
+		}
+		if _, err := oidcService.CompleteLogin("mock", state, code); err != nil {
+			t.Fatalf("complete login: %v", err)
+		}
+		if _, err := oidcService.CompleteLogin("mock", state, code); !errors.Is(err, services.ErrInvalidOIDCState) {
+			t.Errorf("replayed state: %v, want ErrInvalidOIDCState", err)
+		}
+	})
+
+	t.Run("nonce must match", func(t *testing.T) {
+		state, code, grant := start(t, oidcUser("nonce@example.com"))
+		grant.nonce = "replayed-nonce"
+		if _, err := oidcService.CompleteLogin("mock", state, code); !errors.Is(err, services.ErrInvalidIDToken) {
+			t.Errorf("wrong nonce: %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("code only redeems with its verifier", func(t *testing.T) {
+		// A code intercepted from one sign-in is injected into another,
+		// whose code verifier does not match the code's challenge
+		_, stolen, _ := start(t, oidcUser("victim@example.com"))
+		state, _, _ := start(t, oidcUser("attacker@example.com"))
+		if _, err := oidcService.CompleteLogin("mock", state, stolen); err == nil {
+			t.Error("code was redeemed by a sign-in it was not issued to")
+		}
+		var users int64
+		database.GetDB().Model(&models.User{}).Where("email = ?", "victim@example.com").Count(&users)
+		if users != 0 {
+			t.Error("an account was created from the intercepted code")
+		}
+	})
+
+	t.Run("signature must verify", func(t *testing.T) {
+		forger, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, code, grant := start(t, oidcUser("forged@example.com"))
+		grant.signer = forger
+		if _, err := oidcService.CompleteLogin("mock", state, code); !errors.Is(err, services.ErrInvalidIDToken) {
+			t.Errorf("forged token: %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("email must be verified", func(t *testing.T) {
+		claims := oidcUser("unverified@example.com")
+		claims["email_verified"] = false
+		state, code, _ := start(t, claims)
+		if _, err := oidcService.CompleteLogin("mock", state, code); !errors.Is(err, services.ErrOIDCEmailNotVerified) {
+			t.Errorf("unverified email: %v, want ErrOIDCEmailNotVerified", err)
+		}
+	})
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// invitationTTL is how long an emailed invitation to an organisation works
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrOrganizationNotFound     = errors.New("organization not found")
+	ErrOrganizationNotAllowed   = errors.New("only investors and developers can belong to an organization, and only to one of their kind")
+	ErrAlreadyInOrganization    = errors.New("user already belongs to an organization")
+	ErrNotOrganizationOwner     = errors.New("only organization owners can do this")
+	ErrNotOrganizationMember    = errors.New("user is not a member of this organization")
+	ErrInvalidOrganizationRole  = errors.New("role must be owner, member or viewer")
+	ErrLastOrganizationOwner    = errors.New("an organization needs at least one owner")
+	ErrInvitationNotFound       = errors.New("invitation not found")
+	ErrInvalidInvitation        = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch  = errors.New("this invitation was sent to a different email address")
+	ErrOrganizationNameRequired = errors.New("organization name is required")
+)
+
+// OrganizationService manages organisations, their members and invitations
+type OrganizationService struct {
+	authService *AuthService
+}
+
+func NewOrganizationService(authService *AuthService) *OrganizationService {
+	return &OrganizationService{authService: authService}
+}
+
+// OrganizationMembership returns the user's membership with its
+// organisation, or nil if they do not belong to one
+func OrganizationMembership(userID uuid.UUID) *models.OrganizationMember {
+	var member models.OrganizationMember
+	if err := database.GetDB().Preload("Organization").First(&member, "user_id = ?", userID).Error; err != nil {
+		return nil
+	}
+	return &member
+}
+
+// ActingOrganizationID returns the organisation the user acts for, which
+// their new NDAs, payments, offers and projects belong to. It is nil if they
+// are in none or only view it.
+func ActingOrganizationID(userID uuid.UUID) *uuid.UUID {
+	member := OrganizationMembership(userID)
+	if member == nil || !member.Role.CanAct() {
+		return nil
+	}
+	return &member.OrganizationID
+}
+
+// Create forms an organisation of the kind matching the user's role, with the
+// user as its first owner
+func (s *OrganizationService) Create(userID uuid.UUID, name string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrOrganizationNameRequired
+	}
+
+	user, err := s.authService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	orgType := models.OrganizationTypeFor(user.Role)
+	if orgType == "" {
+		return nil, ErrOrganizationNotAllowed
+	}
+
+	org := &models.Organization{Name: name, Type: orgType}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return addMember(tx, org.ID, userID, models.OrganizationRoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(org.ID)
+}
+
+// Get returns an organisation with its members and pending invitations
+func (s *OrganizationService) Get(orgID uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	err := database.GetDB().
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User").
+		Preload("Invitations", "accepted_at IS NULL AND expires_at > ?", time.Now()).
+		First(&org, "id = ?", orgID).Error
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return &org, nil
+}
+
+// Invite emails an invitation to join the organisation with a role. Only
+// owners invite, and a new invitation replaces any pending one to the same
+// address. The returned token is the one to put in the email.
+func (s *OrganizationService) Invite(ownerID, orgID uuid.UUID, email string, role models.OrganizationRole) (*models.OrganizationInvitation, string, error) {
+	if !validOrganizationRole(role) {
+		return nil, "", ErrInvalidOrganizationRole
+	}
+	if _, err := requireOwner(database.GetDB(), ownerID, orgID); err != nil {
+		return nil, "", err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	var existing models.User
+	if err := database.GetDB().Where("LOWER(email) = ?", email).First(&existing).Error; err == nil {
+		if OrganizationMembership(existing.ID) != nil {
+			return nil, "", ErrAlreadyInOrganization
+		}
+	}
+
+	token, err := s.authService.GenerateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &models.OrganizationInvitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      models.HashToken(token),
+		InvitedByID:    ownerID,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ? AND email = ? AND accepted_at IS NULL", orgID, email).
+			Delete(&models.OrganizationInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// RevokeInvitation deletes a pending invitation
+func (s *OrganizationService) RevokeInvitation(ownerID, orgID, invitationID uuid.UUID) error {
+	db := database.GetDB()
+	if _, err := requireOwner(db, ownerID, orgID); err != nil {
+		return err
+	}
+
+	result := db.Where("id = ? AND organization_id = ? AND accepted_at IS NULL", invitationID, orgID).
+		Delete(&models.OrganizationInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the organisation they were invited to.
+// The invitation must have been sent to the user's email address, which
+// receiving it proves, so the address counts as verified afterwards.
+func (s *OrganizationService) AcceptInvitation(userID uuid.UUID, token string) (*models.OrganizationMember, error) {
+	user, err := s.authService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := database.GetDB()
+
+	var invitation models.OrganizationInvitation
+	if err := db.Preload("Organization").First(&invitation, "token_hash = ?", models.HashToken(token)).Error; err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) || invitation.Organization == nil {
+		return nil, ErrInvalidInvitation
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if models.OrganizationTypeFor(user.Role) != invitation.Organization.Type {
+		return nil, ErrOrganizationNotAllowed
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OrganizationInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvitation
+		}
+
+		if err := addMember(tx, invitation.OrganizationID, userID, invitation.Role); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("email_verified", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return OrganizationMembership(userID), nil
+}
+
+// UpdateMemberRole changes a member's role. The last owner cannot be demoted.
+func (s *OrganizationService) UpdateMemberRole(ownerID, orgID, userID uuid.UUID, role models.OrganizationRole) (*models.OrganizationMember, error) {
+	if !validOrganizationRole(role) {
+		return nil, ErrInvalidOrganizationRole
+	}
+
+	var member models.OrganizationMember
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if _, err := requireOwner(tx, ownerID, orgID); err != nil {
+			return err
+		}
+		if err := tx.First(&member, "organization_id = ? AND user_id = ?", orgID, userID).Error; err != nil {
+			return ErrNotOrganizationMember
+		}
+
+		if member.Role == models.OrganizationRoleOwner && role != models.OrganizationRoleOwner {
+			if err := keepAnOwner(tx, orgID); err != nil {
+				return err
+			}
+		}
+
+		member.Role = role
+		return tx.Model(&member).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// RemoveMember takes a user out of the organisation. Owners remove anyone;
+// other members only themselves. What the organisation owns stays with it.
+// An organisation whose last member leaves is deleted.
+func (s *OrganizationService) RemoveMember(actorID, orgID, userID uuid.UUID) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if actorID != userID {
+			if _, err := requireOwner(tx, actorID, orgID); err != nil {
+				return err
+			}
+		}
+
+		var member models.OrganizationMember
+		if err := tx.First(&member, "organization_id = ? AND user_id = ?", orgID, userID).Error; err != nil {
+			return ErrNotOrganizationMember
+		}
+
+		return removeMember(tx, &member)
+	})
+}
+
+// removeMember deletes a membership, keeping an owner while others remain.
+// An organisation left without members is deleted.
+func removeMember(tx *gorm.DB, member *models.OrganizationMember) error {
+	orgID := member.OrganizationID
+
+	var remaining int64
+	if err := tx.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND id <> ?", orgID, member.ID).
+		Count(&remaining).Error; err != nil {
+		return err
+	}
+
+	if member.Role == models.OrganizationRoleOwner && remaining > 0 {
+		if err := keepAnOwner(tx, orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Delete(member).Error; err != nil {
+		return err
+	}
+
+	if remaining == 0 {
+		if err := tx.Where("organization_id = ?", orgID).Delete(&models.OrganizationInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Organization{}, "id = ?", orgID).Error
+	}
+	return nil
+}
+
+// addMember puts a user in an organisation. The unique index on user_id
+// keeps a user from joining two at once.
+func addMember(tx *gorm.DB, orgID, userID uuid.UUID, role models.OrganizationRole) error {
+	var existing int64
+	if err := tx.Model(&models.OrganizationMember{}).Where("user_id = ?", userID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrAlreadyInOrganization
+	}
+
+	return tx.Create(&models.OrganizationMember{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	}).Error
+}
+
+// requireOwner returns the actor's membership if they own the organisation
+func requireOwner(tx *gorm.DB, actorID, orgID uuid.UUID) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := tx.First(&member, "organization_id = ? AND user_id = ?", orgID, actorID).Error; err != nil {
+		return nil, ErrNotOrganizationOwner
+	}
+	if member.Role != models.OrganizationRoleOwner {
+		return nil, ErrNotOrganizationOwner
+	}
+	return &member, nil
+}
+
+// keepAnOwner fails unless the organisation has another owner, for before
+// an owner is demoted or removed
+func keepAnOwner(tx *gorm.DB, orgID uuid.UUID) error {
+	var owners int64
+	if err := tx.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", orgID, models.OrganizationRoleOwner).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOrganizationOwner
+	}
+	return nil
+}
+
+func validOrganizationRole(role models.OrganizationRole) bool {
+	switch role {
+	case models.OrganizationRoleOwner, models.OrganizationRoleMember, models.OrganizationRoleViewer:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import "errors"
+
+// ErrIntentNotFound is returned when a provider does not know a payment intent
+var ErrIntentNotFound = errors.New("payment intent not found at provider")
+
+// PaymentProvider is a payment gateway that collects and refunds view fees.
+// PaymentService only talks to gateways through this interface.
+type PaymentProvider interface {
+	// Name identifies the provider, e.g. in processed webhook records
+	Name() string
+
+	// CreateIntent starts a payment the client then completes with the provider
+	CreateIntent(req IntentRequest) (*Intent, error)
+
+	// GetIntent retrieves the current state of a payment
+	GetIntent(intentID string) (*Intent, error)
+
+	// Refund pays back part or all of a succeeded payment and returns the
+	// refund ID. Providers that ignore RefundRequest.IdempotencyKey must also
+	// implement RefundFinder.
+	Refund(req RefundRequest) (string, error)
+
+	// SignatureHeader names the HTTP header carrying the webhook signature
+	SignatureHeader() string
+
+	// ParseWebhook verifies a webhook signature and translates the payload
+	ParseWebhook(payload []byte, signature string) (*ProviderEvent, error)
+}
+
+// RefundFinder is implemented by providers without idempotency keys. A retried
+// refund looks for one already made instead of refunding again.
+type RefundFinder interface {
+	// FindRefund returns the ID of a refund made on an intent, or "" if none was
+	FindRefund(intentID string) (string, error)
+}
+
+type IntentStatus string
+
+const (
+	IntentStatusPending   IntentStatus = "pending"
+	IntentStatusSucceeded IntentStatus = "succeeded"
+	IntentStatusFailed    IntentStatus = "failed"
+)
+
+// IntentRequest describes a payment to collect
+type IntentRequest struct {
+	Reference     string // Our payment ID, for providers that take a merchant reference
+	Amount        int64  // Amount in cents
+	Currency      string
+	Description   string
+	CustomerEmail string
+	Metadata      map[string]string
+}
+
+// Intent is a provider's view of a payment
+type Intent struct {
+	ID           string            `json:"id"`
+	ClientSecret string            `json:"-"`
+	RedirectURL  string            `json:"redirect_url,omitempty"` // Hosted checkout page, if the provider uses one
+	Status       IntentStatus      `json:"status"`
+	Amount       int64             `json:"amount"`
+	Currency     string            `json:"currency"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	ReceiptURL   string            `json:"receipt_url,omitempty"`
+}
+
+// RefundRequest describes money to pay back on an intent
+type RefundRequest struct {
+	IntentID       string
+	Amount         int64 // Amount in cents
+	Reason         string
+	IdempotencyKey string // Retries with the same key refund only once
+	Metadata       map[string]string
+}
+
+type ProviderEventKind string
+
+const (
+	EventIntentSucceeded ProviderEventKind = "intent_succeeded"
+	EventIntentFailed    ProviderEventKind = "intent_failed"
+	EventChargeRefunded  ProviderEventKind = "charge_refunded"
+)
+
+// ProviderEvent is a verified webhook event translated from the provider's format.
+// Kind is empty for event types the platform does not act on.
+type ProviderEvent struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"` // Provider's own event type
+	Kind           ProviderEventKind `json:"kind"`
+	IntentID       string            `json:"intent_id"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	ReceiptURL     string            `json:"receipt_url,omitempty"`
+	Amount         int64             `json:"amount,omitempty"`   // Collected, on succeeded intents
+	Currency       string            `json:"currency,omitempty"` // Of Amount
+	AmountRefunded int64             `json:"amount_refunded,omitempty"`
+	FullyRefunded  bool              `json:"fully_refunded,omitempty"`
+}
@@ -2,236 +2,709 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v76"
-	"github.com/stripe/stripe-go/v76/paymentintent"
 	"github.com/ukuvago/angel-platform/internal/config"
 	"github.com/ukuvago/angel-platform/internal/database"
 	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrWebhookNotConfigured is returned when no webhook signing secret is set
+	ErrWebhookNotConfigured = errors.New("webhook secret not configured")
+
+	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrPaymentForbidden        = errors.New("payment does not belong to this account")
+	ErrPaymentAlreadyProcessed = errors.New("payment already processed")
+	ErrPaymentIntentMismatch   = errors.New("payment intent does not match this payment")
+	ErrPaymentNotSuccessful    = errors.New("payment not successful")
+	ErrPaymentAmountMismatch   = errors.New("amount paid does not match this payment")
+	ErrDemoModeDisabled        = errors.New("demo payments are disabled on this server")
+	ErrPaymentsUnavailable     = errors.New("payments are not configured on this server")
+	ErrNoViewsRemaining        = errors.New("no remaining project views")
+	ErrPlanNotFound            = errors.New("pricing plan not found")
 )
 
 type PaymentService struct {
-	config *config.Config
+	config    *config.Config
+	provider  PaymentProvider            // Default provider, nil when none is configured
+	providers map[string]PaymentProvider // All configured providers by name
 }
 
+// NewPaymentService uses Stripe when a secret key is configured, and the
+// fake provider in demo mode. Paystack is added when its key is set
+// and serves the currencies mapped to it in config.CurrencyProviders.
 func NewPaymentService(cfg *config.Config) *PaymentService {
+	var provider PaymentProvider
 	if cfg.StripeSecretKey != "" {
-		stripe.Key = cfg.StripeSecretKey
+		provider = NewStripeProvider(cfg.StripeSecretKey, cfg.StripeWebhookSecret)
+	} else if cfg.DemoMode {
+		provider = NewFakeProvider(cfg.StripeWebhookSecret)
+	}
+
+	s := NewPaymentServiceWithProvider(cfg, provider)
+	if cfg.PaystackSecretKey != "" {
+		s.AddProvider(NewPaystackProvider(cfg.PaystackSecretKey, cfg.PaystackBaseURL, cfg.AppURL+"/payments/callback"))
+	}
+	return s
+}
+
+// NewPaymentServiceWithProvider creates a payment service backed by the given default provider
+func NewPaymentServiceWithProvider(cfg *config.Config, provider PaymentProvider) *PaymentService {
+	s := &PaymentService{config: cfg, providers: make(map[string]PaymentProvider)}
+	if provider != nil {
+		s.provider = provider
+		s.AddProvider(provider)
+	}
+	return s
+}
+
+// AddProvider registers a provider for the currencies mapped to its name
+func (s *PaymentService) AddProvider(provider PaymentProvider) {
+	s.providers[provider.Name()] = provider
+}
+
+// Provider returns a configured payment provider by name, or nil
+func (s *PaymentService) Provider(name string) PaymentProvider {
+	return s.providers[name]
+}
+
+// providerForCurrency picks the provider mapped to a currency, falling back to the default
+func (s *PaymentService) providerForCurrency(currency string) PaymentProvider {
+	if provider, ok := s.providers[s.config.CurrencyProviders[currency]]; ok {
+		return provider
 	}
-	return &PaymentService{config: cfg}
+	return s.provider
 }
 
-// CreatePaymentIntent creates a Stripe payment intent for the view fee
-func (s *PaymentService) CreatePaymentIntent(investorID uuid.UUID) (*models.Payment, string, error) {
+// providerForPayment returns the provider a payment was created with.
+// Payments from before providers were recorded belong to the default provider.
+func (s *PaymentService) providerForPayment(payment *models.Payment) PaymentProvider {
+	if payment.Provider == "" {
+		return s.provider
+	}
+	return s.providers[payment.Provider]
+}
+
+// ListActivePlans returns the pricing plans investors can currently buy
+func (s *PaymentService) ListActivePlans() ([]models.PricingPlan, error) {
 	db := database.GetDB()
 
-	// Check if investor has an active payment with remaining views
-	var existingPayment models.Payment
-	err := db.Where("investor_id = ? AND status = ? AND projects_remaining > 0",
-		investorID, models.PaymentStatusCompleted).First(&existingPayment).Error
-	if err == nil {
-		return nil, "", errors.New("you already have an active payment with remaining project views")
+	var plans []models.PricingPlan
+	err := db.Where("active = ?", true).
+		Order("sort_order ASC, amount ASC").
+		Find(&plans).Error
+
+	return plans, err
+}
+
+// GetPlan returns an active pricing plan, or the default plan if planID is nil
+func (s *PaymentService) GetPlan(planID *uuid.UUID) (*models.PricingPlan, error) {
+	db := database.GetDB()
+
+	query := db.Where("active = ?", true)
+	if planID != nil {
+		query = query.Where("id = ?", *planID)
 	}
 
-	// Create payment record
-	payment := &models.Payment{
-		InvestorID:        investorID,
-		Amount:            s.config.ViewFeeAmount,
-		Currency:          s.config.ViewFeeCurrency,
-		Status:            models.PaymentStatusPending,
-		ProjectsRemaining: s.config.MaxProjectViews,
-		ProjectsTotal:     s.config.MaxProjectViews,
-		Description:       "Project viewing fee - access to view up to 4 projects",
+	var plan models.PricingPlan
+	if err := query.Order("sort_order ASC, amount ASC").First(&plan).Error; err != nil {
+		return nil, ErrPlanNotFound
 	}
 
-	if err := db.Create(payment).Error; err != nil {
+	return &plan, nil
+}
+
+// CreatePaymentIntent creates a provider payment intent for the chosen pricing
+// plan, less any coupon discount. A payment the coupon fully waives is
+// completed straight away without involving a provider.
+func (s *PaymentService) CreatePaymentIntent(investorID uuid.UUID, planID *uuid.UUID, couponCode string) (*models.Payment, string, error) {
+	db := database.GetDB()
+
+	// Check if investor has an active payment with remaining views
+	if _, err := s.GetActivePayment(investorID); err == nil {
+		return nil, "", errors.New("you already have an active payment with remaining project views")
+	}
+
+	plan, err := s.GetPlan(planID)
+	if err != nil {
 		return nil, "", err
 	}
 
-	// Create Stripe payment intent if configured
-	var clientSecret string
-	if s.config.StripeSecretKey != "" {
-		params := &stripe.PaymentIntentParams{
-			Amount:   stripe.Int64(payment.Amount),
-			Currency: stripe.String(payment.Currency),
-			Metadata: map[string]string{
-				"payment_id":  payment.ID.String(),
-				"investor_id": investorID.String(),
-			},
-			AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
-				Enabled: stripe.Bool(true),
-			},
+	var coupon *models.Coupon
+	if couponCode != "" {
+		if coupon, err = s.findCoupon(investorID, plan, couponCode); err != nil {
+			return nil, "", err
 		}
+	}
 
-		pi, err := paymentintent.New(params)
+	// Create payment record; owners and members buy credits for their organisation
+	payment := &models.Payment{
+		InvestorID:     investorID,
+		OrganizationID: ActingOrganizationID(investorID),
+		Amount:         plan.Amount,
+		Currency:       plan.Currency,
+		Status:         models.PaymentStatusPending,
+		ProjectsTotal:  plan.Credits,
+		PlanID:         &plan.ID,
+		Unlimited:      plan.Unlimited,
+		Description:    fmt.Sprintf("Project viewing fee - %s: %s", plan.Name, plan.Summary()),
+	}
+	if plan.Unlimited {
+		payment.ProjectsTotal = 0
+	}
+	if coupon != nil {
+		payment.CouponID = &coupon.ID
+		payment.DiscountAmount = coupon.Discount(plan.Amount)
+		payment.Amount -= payment.DiscountAmount
+		payment.Description += " (coupon " + coupon.Code + ")"
+	}
+
+	if payment.Amount == 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(payment).Error; err != nil {
+				return err
+			}
+			return s.completePayment(tx, payment)
+		})
 		if err != nil {
-			// Rollback payment creation
-			db.Delete(payment)
 			return nil, "", err
 		}
+		return payment, "", nil
+	}
 
-		payment.StripePaymentID = pi.ID
-		payment.StripeClientSecret = pi.ClientSecret
-		clientSecret = pi.ClientSecret
+	provider := s.providerForCurrency(plan.Currency)
+	if provider == nil {
+		return nil, "", ErrPaymentsUnavailable
+	}
+	payment.Provider = provider.Name()
 
-		if err := db.Save(payment).Error; err != nil {
-			return nil, "", err
-		}
-	} else {
-		// Demo mode - no Stripe configured
-		clientSecret = "demo_mode"
+	var investor models.User
+	if err := db.First(&investor, "id = ?", investorID).Error; err != nil {
+		return nil, "", err
+	}
+
+	if err := db.Create(payment).Error; err != nil {
+		return nil, "", err
+	}
+
+	intent, err := provider.CreateIntent(IntentRequest{
+		Reference:     payment.ID.String(),
+		Amount:        payment.Amount,
+		Currency:      payment.Currency,
+		Description:   payment.Description,
+		CustomerEmail: investor.Email,
+		Metadata: map[string]string{
+			"payment_id":  payment.ID.String(),
+			"investor_id": investorID.String(),
+		},
+	})
+	if err != nil {
+		// Rollback payment creation
+		db.Delete(payment)
+		return nil, "", err
 	}
 
-	return payment, clientSecret, nil
+	payment.StripePaymentID = intent.ID
+	payment.StripeClientSecret = intent.ClientSecret
+	payment.CheckoutURL = intent.RedirectURL
+
+	if err := db.Save(payment).Error; err != nil {
+		return nil, "", err
+	}
+
+	return payment, intent.ClientSecret, nil
 }
 
-// ConfirmPayment confirms a payment has been completed
-func (s *PaymentService) ConfirmPayment(paymentID uuid.UUID, stripePaymentID string) (*models.Payment, error) {
+// ConfirmPayment confirms a payment has been completed after verifying it with the provider
+func (s *PaymentService) ConfirmPayment(investorID, paymentID uuid.UUID, stripePaymentID string) (*models.Payment, error) {
 	db := database.GetDB()
 
-	var payment models.Payment
-	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
-		return nil, errors.New("payment not found")
+	payment, err := s.getPendingPayment(investorID, paymentID)
+	if err != nil {
+		return nil, err
 	}
 
-	if payment.Status != models.PaymentStatusPending {
-		return nil, errors.New("payment already processed")
+	provider := s.providerForPayment(payment)
+	if provider == nil {
+		return nil, ErrPaymentsUnavailable
 	}
 
-	// Verify with Stripe if configured
-	if s.config.StripeSecretKey != "" && stripePaymentID != "" {
-		pi, err := paymentintent.Get(stripePaymentID, nil)
-		if err != nil {
-			return nil, err
-		}
+	// The client may only confirm the intent created for this payment
+	if stripePaymentID == "" {
+		stripePaymentID = payment.StripePaymentID
+	}
+	if stripePaymentID == "" || stripePaymentID != payment.StripePaymentID {
+		return nil, ErrPaymentIntentMismatch
+	}
 
-		if pi.Status != stripe.PaymentIntentStatusSucceeded {
-			return nil, errors.New("payment not successful")
-		}
+	intent, err := provider.GetIntent(stripePaymentID)
+	if err != nil {
+		return nil, err
+	}
 
-		payment.ReceiptURL = string(pi.LatestCharge.ReceiptURL)
+	if intent.Metadata["payment_id"] != payment.ID.String() {
+		return nil, ErrPaymentIntentMismatch
 	}
 
-	now := time.Now()
-	payment.Status = models.PaymentStatusCompleted
-	payment.CompletedAt = &now
+	if intent.Status != IntentStatusSucceeded {
+		return nil, ErrPaymentNotSuccessful
+	}
+
+	if !paidInFull(payment, intent.Amount, intent.Currency) {
+		return nil, ErrPaymentAmountMismatch
+	}
+
+	payment.ReceiptURL = intent.ReceiptURL
 
-	if err := db.Save(&payment).Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return s.completePayment(tx, payment)
+	}); err != nil {
 		return nil, err
 	}
 
-	return &payment, nil
+	return payment, nil
 }
 
-// DemoConfirmPayment confirms payment in demo mode (no Stripe)
-func (s *PaymentService) DemoConfirmPayment(paymentID uuid.UUID) (*models.Payment, error) {
+// DemoConfirmPayment simulates the investor paying with the fake provider,
+// then confirms the payment as usual
+func (s *PaymentService) DemoConfirmPayment(investorID, paymentID uuid.UUID) (*models.Payment, error) {
+	payment, err := s.getPendingPayment(investorID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	fake, ok := s.providerForPayment(payment).(*FakeProvider)
+	if !ok {
+		return nil, ErrDemoModeDisabled
+	}
+
+	if err := fake.CompleteIntent(payment.StripePaymentID); err != nil {
+		return nil, ErrPaymentIntentMismatch
+	}
+
+	return s.ConfirmPayment(investorID, paymentID, payment.StripePaymentID)
+}
+
+// getPendingPayment loads a payment owned by the investor that is awaiting confirmation
+func (s *PaymentService) getPendingPayment(investorID, paymentID uuid.UUID) (*models.Payment, error) {
 	db := database.GetDB()
 
 	var payment models.Payment
 	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
-		return nil, errors.New("payment not found")
+		return nil, ErrPaymentNotFound
+	}
+
+	if payment.InvestorID != investorID {
+		return nil, ErrPaymentForbidden
 	}
 
 	if payment.Status != models.PaymentStatusPending {
-		return nil, errors.New("payment already processed")
+		return nil, ErrPaymentAlreadyProcessed
 	}
 
+	return &payment, nil
+}
+
+// completePayment marks a payment as completed, grants its view credits and
+// invoices it. The update only applies if the payment is still in the status
+// it was loaded with, so a payment can never be completed twice.
+func (s *PaymentService) completePayment(tx *gorm.DB, payment *models.Payment) error {
 	now := time.Now()
+
+	// Validity windows start when the payment completes
+	validityDays := s.config.CreditValidityDays
+	if payment.PlanID != nil {
+		var plan models.PricingPlan
+		if err := tx.Unscoped().First(&plan, "id = ?", *payment.PlanID).Error; err != nil {
+			return err
+		}
+		if plan.ValidityDays > 0 {
+			validityDays = plan.ValidityDays
+		}
+	}
+	expiresAt := creditExpiry(now, validityDays)
+
+	result := tx.Model(&models.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, payment.Status).
+		Updates(map[string]interface{}{
+			"status":            models.PaymentStatusCompleted,
+			"completed_at":      now,
+			"expires_at":        expiresAt,
+			"receipt_url":       payment.ReceiptURL,
+			"stripe_payment_id": payment.StripePaymentID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPaymentAlreadyProcessed
+	}
+
+	if err := s.appendCredits(tx, payment, models.CreditEntryPurchase, payment.ProjectsTotal, nil, nil, ""); err != nil {
+		return err
+	}
+	if err := s.redeemCoupon(tx, payment); err != nil {
+		return err
+	}
+	// Paid payments are invoiced with the payment, so invoice numbers follow
+	// the order payments complete in
+	if payment.Amount > 0 {
+		if _, err := issueInvoice(tx, s.config.VATRate, payment); err != nil {
+			return err
+		}
+	}
+
 	payment.Status = models.PaymentStatusCompleted
 	payment.CompletedAt = &now
+	payment.ExpiresAt = expiresAt
+	payment.ProjectsRemaining = payment.ProjectsTotal
+	return nil
+}
+
+// paidInFull reports whether the amount and currency a provider collected
+// are what the payment asked for
+func paidInFull(payment *models.Payment, amount int64, currency string) bool {
+	return amount == payment.Amount && strings.EqualFold(currency, payment.Currency)
+}
+
+// ParseWebhookEvent verifies a webhook signature with the named provider and parses the event payload
+func (s *PaymentService) ParseWebhookEvent(providerName string, payload []byte, signature string) (*ProviderEvent, error) {
+	provider := s.providers[providerName]
+	if provider == nil {
+		return nil, ErrWebhookNotConfigured
+	}
+	return provider.ParseWebhook(payload, signature)
+}
+
+// HandleWebhookEvent applies a verified provider event to the matching payment.
+// Events are recorded by ID so redelivered events are only applied once.
+func (s *PaymentService) HandleWebhookEvent(providerName string, event *ProviderEvent) error {
+	db := database.GetDB()
+
+	var processed models.WebhookEvent
+	if err := db.First(&processed, "id = ?", event.ID).Error; err == nil {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch event.Kind {
+		case EventIntentSucceeded:
+			err = s.handlePaymentIntentSucceeded(tx, providerName, event)
+		case EventIntentFailed:
+			err = s.handlePaymentIntentFailed(tx, providerName, event)
+		case EventChargeRefunded:
+			err = s.handleChargeRefunded(tx, providerName, event)
+		default:
+			// Unhandled event types are still recorded so they are not retried
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.WebhookEvent{
+			ID:       event.ID,
+			Provider: providerName,
+			Type:     event.Type,
+		}).Error
+	})
+}
+
+func (s *PaymentService) handlePaymentIntentSucceeded(tx *gorm.DB, providerName string, event *ProviderEvent) error {
+	payment, err := s.findPaymentForIntent(tx, providerName, event.IntentID, event.Metadata)
+	if err != nil {
+		return err
+	}
+	if payment == nil || payment.Status == models.PaymentStatusCompleted ||
+		payment.Status == models.PaymentStatusRefundPending || payment.Status == models.PaymentStatusRefunded {
+		return nil
+	}
 
-	if err := db.Save(&payment).Error; err != nil {
+	if !paidInFull(payment, event.Amount, event.Currency) {
+		log.Printf("Payment %s not completed: %d %s paid, %d %s due", payment.ID, event.Amount, event.Currency, payment.Amount, payment.Currency)
+		return nil
+	}
+
+	if payment.StripePaymentID == "" {
+		payment.StripePaymentID = event.IntentID
+	}
+	if event.ReceiptURL != "" {
+		payment.ReceiptURL = event.ReceiptURL
+	}
+
+	// The client may have confirmed the payment concurrently
+	if err := s.completePayment(tx, payment); err != nil && !errors.Is(err, ErrPaymentAlreadyProcessed) {
+		return err
+	}
+	return nil
+}
+
+func (s *PaymentService) handlePaymentIntentFailed(tx *gorm.DB, providerName string, event *ProviderEvent) error {
+	payment, err := s.findPaymentForIntent(tx, providerName, event.IntentID, event.Metadata)
+	if err != nil {
+		return err
+	}
+	if payment == nil || payment.Status != models.PaymentStatusPending {
+		return nil
+	}
+
+	payment.Status = models.PaymentStatusFailed
+	return tx.Save(payment).Error
+}
+
+func (s *PaymentService) handleChargeRefunded(tx *gorm.DB, providerName string, event *ProviderEvent) error {
+	payment, err := s.findPaymentForIntent(tx, providerName, event.IntentID, event.Metadata)
+	if err != nil {
+		return err
+	}
+	if payment == nil || payment.Status == models.PaymentStatusRefunded {
+		return nil
+	}
+
+	// A refund started here settles once the provider has paid back what was
+	// asked for; one started at the provider must cover the whole payment
+	if payment.Status == models.PaymentStatusRefundPending {
+		if event.AmountRefunded < payment.RefundedAmount {
+			log.Printf("Refund of %d on payment %s is short of the %d requested", event.AmountRefunded, payment.ID, payment.RefundedAmount)
+			return nil
+		}
+	} else if !event.FullyRefunded && event.AmountRefunded < payment.Amount {
+		log.Printf("Partial refund of %d on payment %s not applied", event.AmountRefunded, payment.ID)
+		return nil
+	}
+
+	// A refunded payment can no longer be used, so void its unused credits
+	balance, err := s.CreditBalance(tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if balance > 0 {
+		if err := s.appendCredits(tx, payment, models.CreditEntryRefund, -balance, nil, nil, "Refunded at payment provider"); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	payment.Status = models.PaymentStatusRefunded
+	payment.RefundedAmount = event.AmountRefunded
+	payment.RefundedAt = &now
+	return tx.Save(payment).Error
+}
+
+// findPaymentForIntent looks up a payment by its provider intent ID, falling
+// back to the payment_id metadata set in CreatePaymentIntent. Returns nil if the
+// intent does not belong to a payment made with the named provider.
+func (s *PaymentService) findPaymentForIntent(tx *gorm.DB, providerName, intentID string, metadata map[string]string) (*models.Payment, error) {
+	var payment models.Payment
+	err := tx.Where("stripe_payment_id = ?", intentID).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		paymentID, parseErr := uuid.Parse(metadata["payment_id"])
+		if parseErr != nil {
+			return nil, nil
+		}
+		err = tx.First(&payment, "id = ?", paymentID).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 
+	if provider := s.providerForPayment(&payment); provider == nil || provider.Name() != providerName {
+		return nil, nil
+	}
+
 	return &payment, nil
 }
 
-// GetActivePayment gets an investor's active payment with remaining views
+// GetActivePayment gets an investor's unexpired completed payment that is
+// unlimited or has a positive credit balance, including those of the
+// organisation they act for. Credits that lapse soonest are used first; among
+// payments that never lapse the newest is picked.
 func (s *PaymentService) GetActivePayment(investorID uuid.UUID) (*models.Payment, error) {
 	db := database.GetDB()
 
+	owned := db.Where("payments.investor_id = ?", investorID)
+	if orgID := ActingOrganizationID(investorID); orgID != nil {
+		owned = owned.Or("payments.organization_id = ?", *orgID)
+	}
+
 	var payment models.Payment
-	err := db.Where("investor_id = ? AND status = ? AND projects_remaining > 0",
-		investorID, models.PaymentStatusCompleted).
-		Order("created_at DESC").
+	err := db.Joins("LEFT JOIN (?) AS balances ON balances.payment_id = payments.id", creditBalances(db)).
+		Where(owned).
+		Where("payments.status = ?", models.PaymentStatusCompleted).
+		Where("payments.unlimited = ? OR balances.balance > 0", true).
+		Where("payments.expires_at IS NULL OR payments.expires_at > ?", time.Now()).
+		Order("CASE WHEN payments.expires_at IS NULL THEN 1 ELSE 0 END, payments.expires_at ASC, payments.created_at DESC").
 		First(&payment).Error
 
 	if err != nil {
 		return nil, err
 	}
 
+	payment.ProjectsRemaining, err = s.CreditBalance(db, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &payment, nil
 }
 
-// UseViewCredit decrements the view credit and records the view
+// UseViewCredit consumes a view credit and records the view.
+// Both happen in one transaction that first takes a write lock on the payment
+// row, so concurrent requests can neither overspend the balance nor
+// double-record a view.
 func (s *PaymentService) UseViewCredit(investorID, projectID uuid.UUID) error {
 	db := database.GetDB()
 
-	// Check if already viewed
-	var existingView models.ProjectView
-	if err := db.Where("investor_id = ? AND project_id = ?", investorID, projectID).
-		First(&existingView).Error; err == nil {
-		// Already viewed, no credit needed
-		return nil
-	}
-
-	// Get active payment
 	payment, err := s.GetActivePayment(investorID)
 	if err != nil {
+		if s.HasViewedProject(investorID, projectID) {
+			return nil
+		}
 		return errors.New("no active payment with available views")
 	}
 
-	if !payment.CanViewMore() {
-		return errors.New("no remaining project views")
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Touching the row locks it (row lock on Postgres, write lock on SQLite)
+		// and fails if the payment stopped being usable since it was loaded
+		now := time.Now()
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, models.PaymentStatusCompleted).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			UpdateColumn("updated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoViewsRemaining
+		}
 
-	// Create view record
-	view := &models.ProjectView{
-		InvestorID: investorID,
-		ProjectID:  projectID,
-		PaymentID:  payment.ID,
-		ViewedAt:   time.Now(),
-	}
+		view := &models.ProjectView{
+			InvestorID: investorID,
+			ProjectID:  projectID,
+			PaymentID:  payment.ID,
+			ViewedAt:   time.Now(),
+		}
 
-	if err := db.Create(view).Error; err != nil {
-		return err
-	}
+		// Another member of the organisation may have unlocked the project
+		// while the row lock was awaited
+		var orgViews int64
+		if payment.OrganizationID != nil {
+			if err := organizationViews(tx, *payment.OrganizationID, projectID).Count(&orgViews).Error; err != nil {
+				return err
+			}
+		}
+
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(view)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || orgViews > 0 {
+			// Already viewed, no credit needed
+			return nil
+		}
 
-	// Decrement credit
-	payment.ProjectsRemaining--
-	return db.Save(payment).Error
+		// Unlimited plans still log the view in the ledger, without a charge
+		delta := 0
+		if !payment.Unlimited {
+			balance, err := s.CreditBalance(tx, payment.ID)
+			if err != nil {
+				return err
+			}
+			if balance <= 0 {
+				return ErrNoViewsRemaining
+			}
+			delta = -1
+		}
+
+		if err := s.appendCredits(tx, payment, models.CreditEntryConsume, delta, &projectID, nil, ""); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Project{}).
+			Where("id = ?", projectID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
+	})
 }
 
-// HasViewedProject checks if an investor has already viewed a project
+// HasViewedProject checks if an investor, or anyone with credits of their
+// organisation, has already viewed a project
 func (s *PaymentService) HasViewedProject(investorID, projectID uuid.UUID) bool {
 	db := database.GetDB()
 
 	var view models.ProjectView
 	err := db.Where("investor_id = ? AND project_id = ?", investorID, projectID).First(&view).Error
-	return err == nil
+	if err == nil {
+		return true
+	}
+
+	if member := OrganizationMembership(investorID); member != nil {
+		var views int64
+		organizationViews(db, member.OrganizationID, projectID).Count(&views)
+		return views > 0
+	}
+	return false
+}
+
+// ownedOrOrganizationPayments restricts a query on a table with investor_id
+// and payment_id columns to the investor's rows and those paid for by their
+// organisation
+func ownedOrOrganizationPayments(db *gorm.DB, investorID uuid.UUID) *gorm.DB {
+	query := db.Where("investor_id = ?", investorID)
+	if member := OrganizationMembership(investorID); member != nil {
+		query = query.Or("payment_id IN (?)", db.Model(&models.Payment{}).Select("id").Where("organization_id = ?", member.OrganizationID))
+	}
+	return query
 }
 
-// GetPaymentHistory retrieves payment history for an investor
+// organizationViews selects the views of a project paid with an
+// organisation's credits
+func organizationViews(tx *gorm.DB, orgID, projectID uuid.UUID) *gorm.DB {
+	return tx.Model(&models.ProjectView{}).
+		Joins("JOIN payments ON payments.id = project_views.payment_id").
+		Where("project_views.project_id = ? AND payments.organization_id = ?", projectID, orgID)
+}
+
+// GetPaymentHistory retrieves payment history for an investor, including
+// payments of their organisation
 func (s *PaymentService) GetPaymentHistory(investorID uuid.UUID) ([]models.Payment, error) {
 	db := database.GetDB()
 
+	query := db.Where("investor_id = ?", investorID)
+	if member := OrganizationMembership(investorID); member != nil {
+		query = query.Or("organization_id = ?", member.OrganizationID)
+	}
+
 	var payments []models.Payment
-	err := db.Where("investor_id = ?", investorID).
+	if err := query.
 		Order("created_at DESC").
-		Find(&payments).Error
+		Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.loadCreditBalances(payments); err != nil {
+		return nil, err
+	}
 
-	return payments, err
+	return payments, nil
 }
 
-// GetViewedProjects retrieves projects an investor has viewed
+// GetViewedProjects retrieves projects an investor has viewed, and those
+// unlocked with their organisation's credits
 func (s *PaymentService) GetViewedProjects(investorID uuid.UUID) ([]models.ProjectView, error) {
 	db := database.GetDB()
 
 	var views []models.ProjectView
-	err := db.Where("investor_id = ?", investorID).
+	err := ownedOrOrganizationPayments(db, investorID).
 		Preload("Project").
 		Preload("Project.Category").
 		Order("viewed_at DESC").
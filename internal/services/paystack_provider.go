@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaystackProvider collects payments through Paystack's hosted checkout.
+// Intents are Paystack transactions identified by our own reference.
+type PaystackProvider struct {
+	secretKey   string
+	baseURL     string
+	callbackURL string
+	client      *http.Client
+}
+
+func NewPaystackProvider(secretKey, baseURL, callbackURL string) *PaystackProvider {
+	return &PaystackProvider{
+		secretKey:   secretKey,
+		baseURL:     baseURL,
+		callbackURL: callbackURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *PaystackProvider) Name() string {
+	return "paystack"
+}
+
+func (p *PaystackProvider) SignatureHeader() string {
+	return "X-Paystack-Signature"
+}
+
+// paystackTransaction is the subset of a Paystack transaction we read
+type paystackTransaction struct {
+	ID        int64           `json:"id"`
+	Reference string          `json:"reference"`
+	Status    string          `json:"status"`
+	Amount    int64           `json:"amount"`
+	Currency  string          `json:"currency"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+func (p *PaystackProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	body := map[string]interface{}{
+		"email":        req.CustomerEmail,
+		"amount":       req.Amount,
+		"currency":     strings.ToUpper(req.Currency), // Paystack uses upper-case ISO codes
+		"reference":    req.Reference,
+		"callback_url": p.callbackURL,
+		"metadata":     req.Metadata,
+	}
+
+	var data struct {
+		AuthorizationURL string `json:"authorization_url"`
+		AccessCode       string `json:"access_code"`
+		Reference        string `json:"reference"`
+	}
+	if err := p.call(http.MethodPost, "/transaction/initialize", body, &data); err != nil {
+		return nil, err
+	}
+
+	return &Intent{
+		ID:           data.Reference,
+		ClientSecret: data.AccessCode,
+		RedirectURL:  data.AuthorizationURL,
+		Status:       IntentStatusPending,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		Metadata:     copyMetadata(req.Metadata),
+	}, nil
+}
+
+// GetIntent verifies a transaction by reference
+func (p *PaystackProvider) GetIntent(intentID string) (*Intent, error) {
+	var tx paystackTransaction
+	if err := p.call(http.MethodGet, "/transaction/verify/"+url.PathEscape(intentID), nil, &tx); err != nil {
+		return nil, err
+	}
+
+	intent := &Intent{
+		ID:       tx.Reference,
+		Amount:   tx.Amount,
+		Currency: strings.ToLower(tx.Currency),
+		Metadata: paystackMetadata(tx.Metadata),
+	}
+
+	switch tx.Status {
+	case "success":
+		intent.Status = IntentStatusSucceeded
+	case "failed", "reversed":
+		intent.Status = IntentStatusFailed
+	default:
+		// "abandoned" only means the customer has not finished checkout yet
+		intent.Status = IntentStatusPending
+	}
+
+	return intent, nil
+}
+
+// Refund refunds a transaction by reference. Paystack has no idempotency keys,
+// so PaymentService looks a retried refund up with FindRefund first.
+func (p *PaystackProvider) Refund(req RefundRequest) (string, error) {
+	body := map[string]interface{}{
+		"transaction": req.IntentID,
+		"amount":      req.Amount,
+	}
+	if req.Reason != "" {
+		body["merchant_note"] = req.Reason
+	}
+
+	var data struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.call(http.MethodPost, "/refund", body, &data); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(data.ID, 10), nil
+}
+
+// FindRefund returns the first refund of a transaction that has not failed
+func (p *PaystackProvider) FindRefund(intentID string) (string, error) {
+	var refunds []struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := p.call(http.MethodGet, "/refund?transaction="+url.QueryEscape(intentID), nil, &refunds); err != nil {
+		return "", err
+	}
+
+	for _, refund := range refunds {
+		if refund.Status != "failed" {
+			return strconv.FormatInt(refund.ID, 10), nil
+		}
+	}
+	return "", nil
+}
+
+// ParseWebhook checks the HMAC-SHA512 signature Paystack computes over the
+// payload with the secret key
+func (p *PaystackProvider) ParseWebhook(payload []byte, signature string) (*ProviderEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, errors.New("invalid webhook signature")
+	}
+	mac := hmac.New(sha512.New, []byte(p.secretKey))
+	mac.Write(payload)
+	if !hmac.Equal(expected, mac.Sum(nil)) {
+		return nil, errors.New("invalid webhook signature")
+	}
+
+	var body struct {
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, err
+	}
+
+	result := &ProviderEvent{Type: body.Event}
+
+	switch body.Event {
+	case "charge.success":
+		var tx paystackTransaction
+		if err := json.Unmarshal(body.Data, &tx); err != nil {
+			return nil, err
+		}
+
+		// Paystack events carry no ID of their own
+		result.ID = fmt.Sprintf("paystack_%s_%d", body.Event, tx.ID)
+		result.Kind = EventIntentSucceeded
+		result.IntentID = tx.Reference
+		result.Metadata = paystackMetadata(tx.Metadata)
+		result.Amount = tx.Amount
+		result.Currency = strings.ToLower(tx.Currency)
+	case "refund.processed":
+		var refund struct {
+			ID                   int64  `json:"id"`
+			TransactionReference string `json:"transaction_reference"`
+			Amount               int64  `json:"amount"`
+		}
+		if err := json.Unmarshal(body.Data, &refund); err != nil {
+			return nil, err
+		}
+
+		result.ID = fmt.Sprintf("paystack_%s_%d", body.Event, refund.ID)
+		result.Kind = EventChargeRefunded
+		result.IntentID = refund.TransactionReference
+		result.AmountRefunded = refund.Amount
+	default:
+		sum := sha512.Sum512(payload)
+		result.ID = "paystack_" + body.Event + "_" + hex.EncodeToString(sum[:8])
+	}
+
+	return result, nil
+}
+
+// call sends a request to the Paystack API and decodes the data field of the response
+func (p *PaystackProvider) call(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Status  bool            `json:"status"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("paystack: unreadable response (HTTP %d)", resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrIntentNotFound
+	}
+	if resp.StatusCode >= 300 || !envelope.Status {
+		return fmt.Errorf("paystack: %s", envelope.Message)
+	}
+
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// paystackMetadata reads the metadata we attached to a transaction. Paystack
+// echoes back whatever was sent, which is an empty string when nothing was.
+func paystackMetadata(raw json.RawMessage) map[string]string {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		}
+	}
+	return result
+}
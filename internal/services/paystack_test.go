@@ -0,0 +1,262 @@
+package services_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+const paystackKey = "sk_test_fixture"
+
+// paystackStandIn serves responses recorded from the Paystack API, in
+// testdata/paystack, for the transactions it has seen initialized
+type paystackStandIn struct {
+	*httptest.Server
+	t *testing.T
+
+	mu         sync.Mutex
+	verify     string            // Fixture served when a transaction is verified
+	investors  map[string]string // Investor ID per transaction reference
+	refunds    map[string]int    // Refunds made per transaction reference
+	dropRefund bool              // Whether a refund is made but its response lost
+}
+
+func newPaystackStandIn(t *testing.T) *paystackStandIn {
+	p := &paystackStandIn{t: t, verify: "transaction_verify_success", investors: map[string]string{}, refunds: map[string]int{}}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *paystackStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+paystackKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status":false,"message":"Invalid key"}`))
+		return
+	}
+
+	var body struct {
+		Reference   string            `json:"reference"`
+		Transaction string            `json:"transaction"`
+		Metadata    map[string]string `json:"metadata"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/transaction/initialize":
+		p.investors[body.Reference] = body.Metadata["investor_id"]
+		w.Write(paystackFixture(p.t, "transaction_initialize", body.Reference, body.Metadata["investor_id"]))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/transaction/verify/"):
+		reference := strings.TrimPrefix(r.URL.Path, "/transaction/verify/")
+		investor, ok := p.investors[reference]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":false,"message":"Transaction reference not found"}`))
+			return
+		}
+		w.Write(paystackFixture(p.t, p.verify, reference, investor))
+	case r.Method == http.MethodPost && r.URL.Path == "/refund":
+		p.refunds[body.Transaction]++
+		if p.dropRefund {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Write(paystackFixture(p.t, "refund", body.Transaction, p.investors[body.Transaction]))
+	case r.Method == http.MethodGet && r.URL.Path == "/refund":
+		reference := r.URL.Query().Get("transaction")
+		if p.refunds[reference] == 0 {
+			w.Write([]byte(`{"status":true,"message":"Refunds retrieved","data":[]}`))
+			return
+		}
+		w.Write(paystackFixture(p.t, "refund_list", reference, p.investors[reference]))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":false,"message":"Not found"}`))
+	}
+}
+
+func (p *paystackStandIn) setVerify(fixture string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.verify = fixture
+}
+
+func (p *paystackStandIn) setDropRefund(drop bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropRefund = drop
+}
+
+func (p *paystackStandIn) refundCount(reference string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refunds[reference]
+}
+
+// paystackFixture reads a recorded response or webhook, filled in with our
+// transaction reference and investor
+func paystackFixture(t *testing.T, name, reference, investorID string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "paystack", name+".json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return []byte(strings.NewReplacer("REFERENCE", reference, "INVESTOR", investorID).Replace(string(data)))
+}
+
+// signPaystack signs a webhook payload the way Paystack does
+func signPaystack(payload []byte) string {
+	mac := hmac.New(sha512.New, []byte(paystackKey))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// paystackPayment starts a payment in rand, which is collected through the
+// stand-in Paystack
+func paystackPayment(t *testing.T) (*services.PaymentService, *paystackStandIn, *models.User, *models.Payment) {
+	t.Helper()
+	paystack := newPaystackStandIn(t)
+
+	cfg := testutil.Database(t)
+	cfg.PaystackSecretKey = paystackKey
+	cfg.PaystackBaseURL = paystack.URL
+	cfg.CurrencyProviders = map[string]string{"zar": "paystack"}
+	paymentService := services.NewPaymentService(cfg)
+
+	plan := &models.PricingPlan{Name: "Rand", Amount: 50000, Currency: "zar", Credits: 4, Active: true}
+	if err := database.GetDB().Create(plan).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	investor := testutil.User(t, models.RoleInvestor)
+	payment, _, err := paymentService.CreatePaymentIntent(investor.ID, &plan.ID, "")
+	if err != nil {
+		t.Fatalf("create intent: %v", err)
+	}
+	if payment.Provider != "paystack" || payment.CheckoutURL == "" {
+		t.Fatalf("payment not started with paystack: %+v", payment)
+	}
+	return paymentService, paystack, investor, payment
+}
+
+func TestPaystackConfirmPayment(t *testing.T) {
+	tests := []struct {
+		verify string
+		err    error
+		status models.PaymentStatus
+	}{
+		{"transaction_verify_success", nil, models.PaymentStatusCompleted},
+		{"transaction_verify_underpaid", services.ErrPaymentAmountMismatch, models.PaymentStatusPending},
+		{"transaction_verify_wrong_currency", services.ErrPaymentAmountMismatch, models.PaymentStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.verify, func(t *testing.T) {
+			paymentService, paystack, investor, payment := paystackPayment(t)
+			paystack.setVerify(tt.verify)
+
+			_, err := paymentService.ConfirmPayment(investor.ID, payment.ID, "")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("confirm: %v, want %v", err, tt.err)
+			}
+			if got := loadPayment(t, payment.ID).Status; got != tt.status {
+				t.Errorf("status = %s, want %s", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestPaystackWebhooks(t *testing.T) {
+	paymentService, _, investor, payment := paystackPayment(t)
+	reference := payment.ID.String()
+
+	deliver := func(name string) error {
+		payload := paystackFixture(t, name, reference, investor.ID.String())
+		event, err := paymentService.ParseWebhookEvent("paystack", payload, signPaystack(payload))
+		if err != nil {
+			return err
+		}
+		return paymentService.HandleWebhookEvent("paystack", event)
+	}
+
+	payload := paystackFixture(t, "webhook_charge_success", reference, investor.ID.String())
+	if _, err := paymentService.ParseWebhookEvent("paystack", payload, signPaystack([]byte("tampered"))); err == nil {
+		t.Error("webhook with a bad signature was accepted")
+	}
+
+	if err := deliver("webhook_charge_success_underpaid"); err != nil {
+		t.Fatalf("underpaid charge: %v", err)
+	}
+	if got := loadPayment(t, payment.ID).Status; got != models.PaymentStatusPending {
+		t.Fatalf("underpaid charge left the payment %s", got)
+	}
+
+	if err := deliver("webhook_charge_success"); err != nil {
+		t.Fatalf("charge: %v", err)
+	}
+	if got := loadPayment(t, payment.ID).Status; got != models.PaymentStatusCompleted {
+		t.Fatalf("charge left the payment %s", got)
+	}
+
+	admin := testutil.User(t, models.RoleAdmin)
+	refunded, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "test")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundID != "3018284" {
+		t.Errorf("refund: status %s, refund ID %q", refunded.Status, refunded.RefundID)
+	}
+
+	// The refund's own webhook then changes nothing
+	if err := deliver("webhook_refund_processed"); err != nil {
+		t.Fatalf("refund webhook: %v", err)
+	}
+	if got := loadPayment(t, payment.ID); got.Status != models.PaymentStatusRefunded || got.RefundedAmount != payment.Amount {
+		t.Errorf("after refund webhook: status %s, refunded %d", got.Status, got.RefundedAmount)
+	}
+}
+
+// TestPaystackRefundRetry checks a retried refund whose first response was
+// lost finds the refund Paystack made rather than refunding again
+func TestPaystackRefundRetry(t *testing.T) {
+	paymentService, paystack, investor, payment := paystackPayment(t)
+	reference := payment.ID.String()
+	if _, err := paymentService.ConfirmPayment(investor.ID, payment.ID, ""); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	admin := testutil.User(t, models.RoleAdmin)
+	paystack.setDropRefund(true)
+	if _, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "test"); err == nil {
+		t.Fatal("refund succeeded without a response")
+	}
+
+	paystack.setDropRefund(false)
+	refunded, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "retry")
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundID != "3018284" {
+		t.Errorf("after retry: status %s, refund ID %q", refunded.Status, refunded.RefundID)
+	}
+	if got := paystack.refundCount(reference); got != 1 {
+		t.Errorf("paystack asked to refund %d times, want 1", got)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+type RefundMode string
+
+const (
+	RefundModeFull    RefundMode = "full"     // Refund the whole amount paid
+	RefundModeProRata RefundMode = "pro_rata" // Refund the share paid for unused credits
+)
+
+var (
+	ErrRefundNotAllowed   = errors.New("payment cannot be refunded")
+	ErrNothingToRefund    = errors.New("payment has no unused credits to refund")
+	ErrSelfRefundDisabled = errors.New("self-service refunds are disabled on this server")
+	ErrRefundInProgress   = errors.New("a refund of this payment is already in progress")
+)
+
+// refundClaimTTL is how long a call to refund at the provider holds the
+// payment before another attempt may be made. It is well past the providers'
+// own request timeouts.
+const refundClaimTTL = 2 * time.Minute
+
+// RefundPayment refunds a completed payment on behalf of an admin and voids its unused credits
+func (s *PaymentService) RefundPayment(adminID, paymentID uuid.UUID, mode RefundMode, reason string) (*models.Payment, error) {
+	db := database.GetDB()
+
+	var payment models.Payment
+	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	return s.refund(&payment, mode, &adminID, reason)
+}
+
+// RequestRefund refunds the unused credits of an investor's own payment pro-rata
+func (s *PaymentService) RequestRefund(investorID, paymentID uuid.UUID, reason string) (*models.Payment, error) {
+	if !s.config.SelfRefunds {
+		return nil, ErrSelfRefundDisabled
+	}
+
+	db := database.GetDB()
+
+	var payment models.Payment
+	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.InvestorID != investorID {
+		return nil, ErrPaymentForbidden
+	}
+
+	return s.refund(&payment, RefundModeProRata, nil, reason)
+}
+
+// refund pays back a payment in two steps. The first marks it
+// refund_pending and voids its remaining credits; it takes the same row lock
+// as UseViewCredit, so no credit can be spent once a refund has started. The
+// provider is then called outside any transaction, and the payment is marked
+// refunded once it accepts. A payment left pending by a failed call is
+// settled by the provider's refund webhook, or by an admin retrying it.
+//
+// Each call to the provider first claims the payment through
+// refund_attempted_at, so concurrent retries cannot both refund it. A retry
+// repeats the idempotency key of the first call, and with providers that take
+// none looks up the refund the first call may have made.
+func (s *PaymentService) refund(payment *models.Payment, mode RefundMode, actorID *uuid.UUID, reason string) (*models.Payment, error) {
+	if mode != RefundModeFull && mode != RefundModeProRata {
+		return nil, errors.New("invalid refund mode")
+	}
+
+	db := database.GetDB()
+
+	// Only admins retry a refund the provider has not confirmed
+	retry := payment.Status == models.PaymentStatusRefundPending && actorID != nil
+	if retry {
+		if err := s.claimRefundRetry(payment); err != nil {
+			return nil, err
+		}
+	} else if err := s.startRefund(payment, mode, actorID, reason); err != nil {
+		return nil, err
+	}
+
+	refundID, err := s.issueRefund(payment, payment.RefundedAmount, reason, retry)
+	if err != nil {
+		// Whether or not the provider made the refund, a retry now finds or
+		// repeats it safely
+		db.Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, models.PaymentStatusRefundPending).
+			Update("refund_attempted_at", nil)
+		return nil, err
+	}
+
+	now := time.Now()
+	result := db.Model(&models.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, models.PaymentStatusRefundPending).
+		Updates(map[string]interface{}{
+			"status":              models.PaymentStatusRefunded,
+			"refund_id":           refundID,
+			"refunded_at":         now,
+			"refund_attempted_at": nil,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	// Otherwise the refund webhook settled the payment first
+
+	var settled models.Payment
+	if err := db.First(&settled, "id = ?", payment.ID).Error; err != nil {
+		return nil, err
+	}
+	settled.ProjectsRemaining = 0
+	return &settled, nil
+}
+
+// claimRefundRetry claims a pending refund for another call to the provider.
+// It fails while an earlier call may still be in flight.
+func (s *PaymentService) claimRefundRetry(payment *models.Payment) error {
+	db := database.GetDB()
+	now := time.Now()
+
+	result := db.Model(&models.Payment{}).
+		Where("id = ? AND status = ? AND (refund_attempted_at IS NULL OR refund_attempted_at < ?)",
+			payment.ID, models.PaymentStatusRefundPending, now.Add(-refundClaimTTL)).
+		Update("refund_attempted_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var current models.Payment
+		if err := db.Select("status").First(&current, "id = ?", payment.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != models.PaymentStatusRefundPending {
+			// The refund webhook settled the payment meanwhile
+			return ErrPaymentAlreadyProcessed
+		}
+		return ErrRefundInProgress
+	}
+
+	payment.RefundAttemptedAt = &now
+	return nil
+}
+
+// startRefund marks a completed payment refund_pending with the amount to
+// pay back, voids its unused credits, and claims the first call to the
+// provider
+func (s *PaymentService) startRefund(payment *models.Payment, mode RefundMode, actorID *uuid.UUID, reason string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, models.PaymentStatusCompleted).
+			Update("status", models.PaymentStatusRefundPending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if payment.Status == models.PaymentStatusPending || payment.Status == models.PaymentStatusFailed {
+				return ErrRefundNotAllowed
+			}
+			return ErrPaymentAlreadyProcessed
+		}
+
+		balance, err := s.CreditBalance(tx, payment.ID)
+		if err != nil {
+			return err
+		}
+
+		amount, err := s.refundAmount(payment, balance, mode)
+		if err != nil {
+			return err
+		}
+
+		if balance > 0 {
+			if err := s.appendCredits(tx, payment, models.CreditEntryRefund, -balance, nil, actorID, reason); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		payment.Status = models.PaymentStatusRefundPending
+		payment.RefundedAmount = amount
+		payment.RefundAttemptedAt = &now
+		return tx.Model(&models.Payment{}).
+			Where("id = ?", payment.ID).
+			Updates(map[string]interface{}{
+				"refunded_amount":     amount,
+				"refund_attempted_at": now,
+			}).Error
+	})
+}
+
+// refundAmount returns how much a refund pays back. Pro-rata refunds return
+// the share of the amount paid for credits that are still unused.
+func (s *PaymentService) refundAmount(payment *models.Payment, balance int, mode RefundMode) (int64, error) {
+	if mode == RefundModeFull {
+		return payment.Amount, nil
+	}
+
+	// Unlimited plans have no unused credits to prorate against
+	if payment.Unlimited {
+		return 0, ErrRefundNotAllowed
+	}
+	if balance <= 0 || payment.ProjectsTotal <= 0 {
+		return 0, ErrNothingToRefund
+	}
+
+	return payment.Amount * int64(balance) / int64(payment.ProjectsTotal), nil
+}
+
+// issueRefund pays an amount back through the payment provider. Free payments
+// such as admin grants have nothing to pay back.
+func (s *PaymentService) issueRefund(payment *models.Payment, amount int64, reason string, retry bool) (string, error) {
+	if amount == 0 {
+		return "", nil
+	}
+	provider := s.providerForPayment(payment)
+	if provider == nil {
+		return "", ErrPaymentsUnavailable
+	}
+	if payment.StripePaymentID == "" {
+		return "", ErrRefundNotAllowed
+	}
+
+	// The first call may have refunded the payment before it failed
+	if finder, ok := provider.(RefundFinder); ok && retry {
+		refundID, err := finder.FindRefund(payment.StripePaymentID)
+		if err != nil || refundID != "" {
+			return refundID, err
+		}
+	}
+
+	return provider.Refund(RefundRequest{
+		IntentID: payment.StripePaymentID,
+		Amount:   amount,
+		Reason:   reason,
+		// A retried request must not refund the payment twice
+		IdempotencyKey: "refund_" + payment.ID.String(),
+		Metadata:       map[string]string{"payment_id": payment.ID.String()},
+	})
+}
@@ -0,0 +1,179 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// flakyRefunds is the fake provider with refunds that fail while err is set.
+// It records the payment's committed status as each refund call starts.
+type flakyRefunds struct {
+	*services.FakeProvider
+	err      error
+	statuses []models.PaymentStatus
+}
+
+func (p *flakyRefunds) Refund(req services.RefundRequest) (string, error) {
+	var payment models.Payment
+	database.GetDB().First(&payment, "id = ?", req.Metadata["payment_id"])
+	p.statuses = append(p.statuses, payment.Status)
+
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.FakeProvider.Refund(req)
+}
+
+// paidPayment has the investor buy the default plan through the fake provider
+func paidPayment(t *testing.T, paymentService *services.PaymentService, fake *services.FakeProvider, investor *models.User) *models.Payment {
+	t.Helper()
+	payment, _, err := paymentService.CreatePaymentIntent(investor.ID, nil, "")
+	if err != nil {
+		t.Fatalf("create intent: %v", err)
+	}
+	if err := fake.CompleteIntent(payment.StripePaymentID); err != nil {
+		t.Fatalf("complete intent: %v", err)
+	}
+	if payment, err = paymentService.ConfirmPayment(investor.ID, payment.ID, ""); err != nil {
+		t.Fatalf("confirm payment: %v", err)
+	}
+	return payment
+}
+
+func loadPayment(t *testing.T, id interface{}) *models.Payment {
+	t.Helper()
+	var payment models.Payment
+	if err := database.GetDB().First(&payment, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &payment
+}
+
+// TestRefundOutsideTransaction checks the provider is only asked to refund
+// once the refund is committed as pending, and that a failed call leaves the
+// payment pending until an admin retries it
+func TestRefundOutsideTransaction(t *testing.T) {
+	cfg := testutil.Database(t)
+	fake := services.NewFakeProvider("whsec_test")
+	provider := &flakyRefunds{FakeProvider: fake, err: errors.New("provider unavailable")}
+	paymentService := services.NewPaymentServiceWithProvider(cfg, provider)
+
+	admin := testutil.User(t, models.RoleAdmin)
+	investor := testutil.User(t, models.RoleInvestor)
+	project := testutil.Project(t, testutil.User(t, models.RoleDeveloper), models.ProjectStatusApproved)
+	payment := paidPayment(t, paymentService, fake, investor)
+
+	if _, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "test"); err == nil {
+		t.Fatal("refund succeeded while the provider was failing")
+	}
+
+	pending := loadPayment(t, payment.ID)
+	if pending.Status != models.PaymentStatusRefundPending || pending.RefundedAmount != payment.Amount {
+		t.Fatalf("after a failed call: status %s, refunded %d; want refund_pending, %d", pending.Status, pending.RefundedAmount, payment.Amount)
+	}
+	if balance, _ := paymentService.CreditBalance(database.GetDB(), payment.ID); balance != 0 {
+		t.Errorf("pending refund left %d credits", balance)
+	}
+	if err := paymentService.UseViewCredit(investor.ID, project.ID); err == nil {
+		t.Error("a credit was spent on a payment being refunded")
+	}
+
+	provider.err = nil
+	refunded, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "retry")
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundID == "" || refunded.RefundedAt == nil {
+		t.Errorf("after retry: %+v", refunded)
+	}
+	if got := fake.RefundedAmount(payment.StripePaymentID); got != payment.Amount {
+		t.Errorf("provider refunded %d, want %d", got, payment.Amount)
+	}
+
+	for _, status := range provider.statuses {
+		if status != models.PaymentStatusRefundPending {
+			t.Errorf("provider called while the payment was committed as %q", status)
+		}
+	}
+}
+
+// TestRefundRetryClaimed checks an admin retry is refused while another
+// call to refund the payment may still be in flight
+func TestRefundRetryClaimed(t *testing.T) {
+	cfg := testutil.Database(t)
+	fake := services.NewFakeProvider("whsec_test")
+	provider := &flakyRefunds{FakeProvider: fake, err: errors.New("provider unavailable")}
+	paymentService := services.NewPaymentServiceWithProvider(cfg, provider)
+
+	admin := testutil.User(t, models.RoleAdmin)
+	payment := paidPayment(t, paymentService, fake, testutil.User(t, models.RoleInvestor))
+	if _, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "test"); err == nil {
+		t.Fatal("refund succeeded while the provider was failing")
+	}
+	provider.err = nil
+
+	// Another admin's retry has just called the provider
+	database.GetDB().Model(&models.Payment{}).Where("id = ?", payment.ID).Update("refund_attempted_at", time.Now())
+	if _, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "retry"); !errors.Is(err, services.ErrRefundInProgress) {
+		t.Fatalf("retry during another: %v, want ErrRefundInProgress", err)
+	}
+	if calls := len(provider.statuses); calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+
+	// A call that never finished stops holding the payment after a while
+	database.GetDB().Model(&models.Payment{}).Where("id = ?", payment.ID).Update("refund_attempted_at", time.Now().Add(-time.Hour))
+	refunded, err := paymentService.RefundPayment(admin.ID, payment.ID, services.RefundModeFull, "retry")
+	if err != nil {
+		t.Fatalf("retry after the claim expired: %v", err)
+	}
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundAttemptedAt != nil {
+		t.Errorf("after retry: status %s, attempted at %v", refunded.Status, refunded.RefundAttemptedAt)
+	}
+}
+
+// TestRefundSettledByWebhook checks the provider's refund webhook settles a
+// refund whose call failed here
+func TestRefundSettledByWebhook(t *testing.T) {
+	cfg := testutil.Database(t)
+	cfg.SelfRefunds = true
+	fake := services.NewFakeProvider("whsec_test")
+	provider := &flakyRefunds{FakeProvider: fake, err: errors.New("timeout")}
+	paymentService := services.NewPaymentServiceWithProvider(cfg, provider)
+
+	investor := testutil.User(t, models.RoleInvestor)
+	payment := paidPayment(t, paymentService, fake, investor)
+
+	if _, err := paymentService.RequestRefund(investor.ID, payment.ID, "changed my mind"); err == nil {
+		t.Fatal("refund succeeded while the provider was failing")
+	}
+	// Investors cannot retry; the refund is the provider's to settle now
+	if _, err := paymentService.RequestRefund(investor.ID, payment.ID, "again"); !errors.Is(err, services.ErrPaymentAlreadyProcessed) {
+		t.Errorf("second request: %v, want ErrPaymentAlreadyProcessed", err)
+	}
+	if len(provider.statuses) != 1 {
+		t.Errorf("provider called %d times, want 1", len(provider.statuses))
+	}
+
+	err := paymentService.HandleWebhookEvent("fake", &services.ProviderEvent{
+		ID:             "evt_refund",
+		Type:           "charge.refunded",
+		Kind:           services.EventChargeRefunded,
+		IntentID:       payment.StripePaymentID,
+		AmountRefunded: payment.Amount,
+	})
+	if err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+
+	settled := loadPayment(t, payment.ID)
+	if settled.Status != models.PaymentStatusRefunded || settled.RefundedAmount != payment.Amount || settled.RefundedAt == nil {
+		t.Errorf("after webhook: status %s, refunded %d", settled.Status, settled.RefundedAmount)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrSessionRevoked      = errors.New("session has ended")
+	ErrAccountSuspended    = errors.New("account has been suspended")
+)
+
+// TokenPair is what signing in or refreshing hands to the client
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // When the access token expires
+	SessionID    uuid.UUID
+}
+
+// StartSession signs a user in on a new device with their password alone
+func (s *AuthService) StartSession(user *models.User, userAgent, ipAddress string) (*TokenPair, error) {
+	return s.startSession(user, false, userAgent, ipAddress)
+}
+
+func (s *AuthService) startSession(user *models.User, twoFactor bool, userAgent, ipAddress string) (*TokenPair, error) {
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	refreshToken, err := s.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: models.HashToken(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		TwoFactor:        twoFactor,
+		ExpiresAt:        now.Add(s.refreshTokenTTL()),
+		LastUsedAt:       now,
+	}
+	if err := database.GetDB().Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session.ID, refreshToken)
+}
+
+// RefreshSession swaps a refresh token for a new pair. Each refresh token works
+// once: presenting one that was already swapped means it has leaked, so the
+// session it belonged to is ended.
+func (s *AuthService) RefreshSession(refreshToken, userAgent, ipAddress string) (*TokenPair, error) {
+	db := database.GetDB()
+	hash := models.HashToken(refreshToken)
+
+	var session models.Session
+	if err := db.First(&session, "refresh_token_hash = ?", hash).Error; err != nil {
+		var reused models.Session
+		if db.First(&reused, "previous_token_hash = ?", hash).Error == nil {
+			s.RevokeSession(reused.UserID, reused.ID)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if !session.IsActive() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Tokens carry the user's current role, so a changed role applies from here on
+	user, err := s.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if user.IsSuspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	newToken, err := s.GenerateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Swapping only the hash we read makes concurrent refreshes with the same
+	// token succeed at most once
+	now := time.Now()
+	result := db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, hash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  models.HashToken(newToken),
+			"previous_token_hash": hash,
+			"expires_at":          now.Add(s.refreshTokenTTL()),
+			"last_used_at":        now,
+			"user_agent":          userAgent,
+			"ip_address":          ipAddress,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issueTokens(user, session.ID, newToken)
+}
+
+// RevokeSession ends one of the user's sessions
+func (s *AuthService) RevokeSession(userID, sessionID uuid.UUID) error {
+	return database.GetDB().Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllSessions ends every session of the user except keep, which may be
+// uuid.Nil to sign the user out everywhere
+func (s *AuthService) RevokeAllSessions(userID, keep uuid.UUID) error {
+	_, err := revokeSessions(database.GetDB(), userID, keep)
+	return err
+}
+
+// revokeSessions ends the user's sessions except keep within a transaction,
+// and returns how many were ended
+func revokeSessions(tx *gorm.DB, userID, keep uuid.UUID) (int64, error) {
+	result := tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keep).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// activeSession loads the session an access token was issued for, provided it
+// is still active
+func (s *AuthService) activeSession(sessionID uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	if err := database.GetDB().Select("id", "two_factor", "revoked_at", "expires_at").First(&session, "id = ?", sessionID).Error; err != nil {
+		return nil, ErrSessionRevoked
+	}
+	if !session.IsActive() {
+		return nil, ErrSessionRevoked
+	}
+	return &session, nil
+}
+
+func (s *AuthService) issueTokens(user *models.User, sessionID uuid.UUID, refreshToken string) (*TokenPair, error) {
+	accessToken, expiresAt, err := s.generateAccessToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		SessionID:    sessionID,
+	}, nil
+}
+
+func (s *AuthService) refreshTokenTTL() time.Duration {
+	return time.Duration(s.config.RefreshTokenTTL) * 24 * time.Hour
+}
@@ -22,6 +22,7 @@ func NewStorageService(cfg *config.Config) *StorageService {
 	os.MkdirAll(cfg.UploadDir, 0755)
 	os.MkdirAll(filepath.Join(cfg.UploadDir, "projects"), 0755)
 	os.MkdirAll(filepath.Join(cfg.UploadDir, "documents"), 0755)
+	os.MkdirAll(cfg.KYCUploadDir, 0700)
 
 	return &StorageService{config: cfg}
 }
@@ -131,6 +132,64 @@ func (s *StorageService) SavePitchDeck(projectID uuid.UUID, file *multipart.File
 	return filepath.Join("projects", projectID.String(), filename), nil
 }
 
+// AllowedKYCExtensions lists valid KYC document extensions
+var AllowedKYCExtensions = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+}
+
+// MaxKYCDocumentSize is the maximum allowed KYC document size (10MB)
+const MaxKYCDocumentSize = 10 * 1024 * 1024
+
+// SaveKYCDocument saves an uploaded KYC document in the private KYC directory
+func (s *StorageService) SaveKYCDocument(submissionID uuid.UUID, file *multipart.FileHeader) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !AllowedKYCExtensions[ext] {
+		return "", fmt.Errorf("invalid file type: %s. Allowed: pdf, jpg, jpeg, png", ext)
+	}
+
+	if file.Size > MaxKYCDocumentSize {
+		return "", fmt.Errorf("file too large. Maximum size is 10MB")
+	}
+
+	submissionDir := filepath.Join(s.config.KYCUploadDir, submissionID.String())
+	if err := os.MkdirAll(submissionDir, 0700); err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("%s_%d%s", uuid.New().String()[:8], time.Now().Unix(), ext)
+
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filepath.Join(submissionDir, filename), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(submissionID.String(), filename), nil
+}
+
+// GetKYCDocumentPath returns the full path of a stored KYC document
+func (s *StorageService) GetKYCDocumentPath(relativePath string) string {
+	return filepath.Join(s.config.KYCUploadDir, relativePath)
+}
+
+// DeleteKYCSubmission deletes all documents stored for a KYC submission
+func (s *StorageService) DeleteKYCSubmission(submissionID uuid.UUID) error {
+	return os.RemoveAll(filepath.Join(s.config.KYCUploadDir, submissionID.String()))
+}
+
 // DeleteProjectImage deletes a project image
 func (s *StorageService) DeleteProjectImage(relativePath string) error {
 	fullPath := filepath.Join(s.config.UploadDir, relativePath)
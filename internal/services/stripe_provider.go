@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/paymentintent"
+	"github.com/stripe/stripe-go/v76/refund"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// StripeProvider collects payments with Stripe PaymentIntents
+type StripeProvider struct {
+	webhookSecret string
+}
+
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	stripe.Key = secretKey
+	return &StripeProvider{webhookSecret: webhookSecret}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+func (p *StripeProvider) SignatureHeader() string {
+	return "Stripe-Signature"
+}
+
+func (p *StripeProvider) CreateIntent(req IntentRequest) (*Intent, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:      stripe.Int64(req.Amount),
+		Currency:    stripe.String(req.Currency),
+		Description: stripe.String(req.Description),
+		Metadata:    req.Metadata,
+		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+	}
+
+	pi, err := paymentintent.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return stripeIntent(pi), nil
+}
+
+func (p *StripeProvider) GetIntent(intentID string) (*Intent, error) {
+	params := &stripe.PaymentIntentParams{}
+	params.AddExpand("latest_charge")
+	pi, err := paymentintent.Get(intentID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return stripeIntent(pi), nil
+}
+
+func (p *StripeProvider) Refund(req RefundRequest) (string, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(req.IntentID),
+		Amount:        stripe.Int64(req.Amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	for k, v := range req.Metadata {
+		params.AddMetadata(k, v)
+	}
+	if req.Reason != "" {
+		params.AddMetadata("reason", req.Reason)
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+
+	r, err := refund.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return r.ID, nil
+}
+
+func (p *StripeProvider) ParseWebhook(payload []byte, signature string) (*ProviderEvent, error) {
+	if p.webhookSecret == "" {
+		return nil, ErrWebhookNotConfigured
+	}
+
+	// The account's API version may differ from the library's; the payload
+	// fields we read are stable across versions.
+	event, err := webhook.ConstructEventWithOptions(payload, signature, p.webhookSecret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProviderEvent{
+		ID:   event.ID,
+		Type: string(event.Type),
+	}
+
+	switch event.Type {
+	case "payment_intent.succeeded", "payment_intent.payment_failed":
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+			return nil, err
+		}
+
+		result.Kind = EventIntentSucceeded
+		if event.Type == "payment_intent.payment_failed" {
+			result.Kind = EventIntentFailed
+		}
+		result.IntentID = pi.ID
+		result.Metadata = pi.Metadata
+		result.Amount = pi.AmountReceived
+		result.Currency = string(pi.Currency)
+		if pi.LatestCharge != nil {
+			result.ReceiptURL = pi.LatestCharge.ReceiptURL
+		}
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return nil, err
+		}
+		if charge.PaymentIntent == nil {
+			return result, nil
+		}
+
+		result.Kind = EventChargeRefunded
+		result.IntentID = charge.PaymentIntent.ID
+		result.Metadata = charge.Metadata
+		result.AmountRefunded = charge.AmountRefunded
+		result.FullyRefunded = charge.Refunded
+	}
+
+	return result, nil
+}
+
+func stripeIntent(pi *stripe.PaymentIntent) *Intent {
+	intent := &Intent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Amount:       pi.Amount,
+		Currency:     string(pi.Currency),
+		Metadata:     pi.Metadata,
+	}
+
+	switch pi.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		intent.Status = IntentStatusSucceeded
+	case stripe.PaymentIntentStatusCanceled:
+		intent.Status = IntentStatusFailed
+	default:
+		intent.Status = IntentStatusPending
+	}
+
+	if pi.LatestCharge != nil {
+		intent.ReceiptURL = pi.LatestCharge.ReceiptURL
+	}
+
+	return intent
+}
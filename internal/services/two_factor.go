@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp    = errors.New("start two-factor setup first")
+	ErrInvalidTwoFactorCode = errors.New("invalid authentication code")
+	ErrInvalidChallenge     = errors.New("invalid or expired sign-in challenge")
+)
+
+const (
+	totpPeriod        = 30 // seconds per time step (RFC 6238)
+	totpDigits        = 6
+	totpSkew          = 1 // steps either side of now still accepted, for clock drift
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+	challengeSubject  = "2fa_challenge"
+)
+
+// SetupTOTP starts enrolment by giving the user a new secret. 2FA stays off
+// until EnableTOTP confirms the authenticator produces matching codes.
+func (s *AuthService) SetupTOTP(userID uuid.UUID) (secret, provisioningURI string, err error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	if err := database.GetDB().Model(user).Update("totp_secret", secret).Error; err != nil {
+		return "", "", err
+	}
+
+	return secret, s.provisioningURI(user.Email, secret), nil
+}
+
+// EnableTOTP turns 2FA on once the user proves their authenticator works, and
+// returns recovery codes to show them once. The session they enrolled from
+// counts as having passed 2FA.
+func (s *AuthService) EnableTOTP(userID, sessionID uuid.UUID, code string) ([]string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if err := s.acceptTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).Where("id = ?", sessionID).Update("two_factor", true).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns 2FA off after checking the password and a current code
+func (s *AuthService) DisableTOTP(userID uuid.UUID, password, code string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if !s.CheckPassword(password, user.PasswordHash) {
+		return errors.New("incorrect password")
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return err
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a
+// current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.acceptTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// IssueChallenge returns a short-lived token proving the user passed the
+// password step. It is exchanged for a session with CompleteChallenge.
+func (s *AuthService) IssueChallenge(user *models.User) (string, error) {
+	claims := &Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   challengeSubject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(challengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    s.config.AppName,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// ChallengeUser returns the user a sign-in challenge was issued to
+func (s *AuthService) ChallengeUser(challengeToken string) (*models.User, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(challengeToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(s.config.JWTSecret), nil
+	})
+	if err != nil || !token.Valid || claims.Subject != challengeSubject {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.GetUserByID(claims.UserID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+	return user, nil
+}
+
+// CompleteChallenge checks the second factor, a TOTP or recovery code, and
+// signs the user in
+func (s *AuthService) CompleteChallenge(challengeToken, code, userAgent, ipAddress string) (*models.User, *TokenPair, error) {
+	user, err := s.ChallengeUser(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.startSession(user, true, userAgent, ipAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func (s *AuthService) verifySecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		return s.acceptTOTP(user, code)
+	}
+	return useRecoveryCode(user.ID, code)
+}
+
+// acceptTOTP checks a TOTP code and records its time step. Recording only
+// moves forward, so a code cannot be replayed even by concurrent requests.
+func (s *AuthService) acceptTOTP(user *models.User, code string) error {
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if !hmac.Equal([]byte(totpCode(user.TOTPSecret, step)), []byte(code)) {
+			continue
+		}
+
+		result := database.GetDB().Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *AuthService) provisioningURI(email, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", s.config.AppName)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(s.config.AppName + ":" + email)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// totpCode computes the RFC 6238 code for a time step
+func totpCode(secret string, step int64) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return ""
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+
+		record := &models.RecoveryCode{UserID: userID, CodeHash: models.HashToken(normalizeRecoveryCode(codes[i]))}
+		if err := tx.Create(record).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// useRecoveryCode marks a matching unused recovery code as used
+func useRecoveryCode(userID uuid.UUID, code string) error {
+	result := database.GetDB().Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, models.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	errUserTokenInvalid = errors.New("user token invalid or used")
+	errUserTokenExpired = errors.New("user token expired")
+)
+
+// issueUserToken creates a token for one purpose, replacing the user's unused
+// earlier tokens for it, so only the newest emailed link works
+func (s *AuthService) issueUserToken(tx *gorm.DB, userID uuid.UUID, purpose models.TokenPurpose, ttl time.Duration, ipAddress string) (string, error) {
+	token, err := s.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Delete(&models.UserToken{}).Error; err != nil {
+		return "", err
+	}
+
+	err = tx.Create(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: models.HashToken(token),
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(ttl),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken marks a token used and returns it. Marking takes a row
+// lock, so of two concurrent requests with the same token only one succeeds.
+func (s *AuthService) consumeUserToken(tx *gorm.DB, purpose models.TokenPurpose, token string) (*models.UserToken, error) {
+	hash := models.HashToken(token)
+	var record models.UserToken
+	if err := tx.First(&record, "token_hash = ? AND purpose = ?", hash, purpose).Error; err != nil {
+		return nil, errUserTokenInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(record.TokenHash), []byte(hash)) != 1 || record.UsedAt != nil {
+		return nil, errUserTokenInvalid
+	}
+
+	now := time.Now()
+	if now.After(record.ExpiresAt) {
+		return nil, errUserTokenExpired
+	}
+
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errUserTokenInvalid
+	}
+
+	record.UsedAt = &now
+	return &record, nil
+}
@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"github.com/ukuvago/angel-platform/internal/testutil"
+)
+
+// TestUseViewCreditConcurrently spends credits from many goroutines at once,
+// two per project, on a payment with fewer credits than projects. The
+// balance must never go below zero and each project is viewed once.
+func TestUseViewCreditConcurrently(t *testing.T) {
+	databases := map[string]func(testing.TB, *config.Config){
+		"sqlite":   testutil.SQLite,
+		"postgres": testutil.Postgres,
+	}
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			cfg := testutil.Config(t)
+			open(t, cfg)
+			testUseViewCreditConcurrently(t, cfg)
+		})
+	}
+}
+
+func testUseViewCreditConcurrently(t *testing.T, cfg *config.Config) {
+	const credits, projectCount = 3, 8
+	paymentService := services.NewPaymentService(cfg)
+
+	admin := testutil.User(t, models.RoleAdmin)
+	investor := testutil.User(t, models.RoleInvestor)
+	developer := testutil.User(t, models.RoleDeveloper)
+	payment, err := paymentService.GrantCredits(admin.ID, investor.ID, credits, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var projects []*models.Project
+	for i := 0; i < projectCount; i++ {
+		projects = append(projects, testutil.Project(t, developer, models.ProjectStatusApproved))
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, project := range append(projects, projects...) {
+		wg.Add(1)
+		go func(project *models.Project) {
+			defer wg.Done()
+			<-start
+			// Running out of credits is expected; the counts below tell
+			paymentService.UseViewCredit(investor.ID, project.ID)
+		}(project)
+	}
+	close(start)
+	wg.Wait()
+
+	db := database.GetDB()
+	balance, err := paymentService.CreditBalance(db, payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 0 {
+		t.Errorf("balance = %d, want 0", balance)
+	}
+
+	var viewed int64
+	for _, project := range projects {
+		var views int64
+		db.Model(&models.ProjectView{}).Where("project_id = ?", project.ID).Count(&views)
+		if views > 1 {
+			t.Errorf("project %s has %d views", project.ID, views)
+		}
+		viewed += views
+
+		var stored models.Project
+		db.First(&stored, "id = ?", project.ID)
+		if int64(stored.ViewCount) != views {
+			t.Errorf("project %s view count = %d, want %d", project.ID, stored.ViewCount, views)
+		}
+	}
+	if viewed != credits {
+		t.Errorf("%d projects viewed, want %d", viewed, credits)
+	}
+
+	var spent int64
+	db.Model(&models.CreditLedgerEntry{}).
+		Where("payment_id = ? AND type = ?", payment.ID, models.CreditEntryConsume).
+		Count(&spent)
+	if spent != credits {
+		t.Errorf("%d credits spent in the ledger, want %d", spent, credits)
+	}
+}
@@ -0,0 +1,205 @@
+// Package testutil sets up the database and users that tests across the
+// platform run against, and sends requests to the router.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ukuvago/angel-platform/internal/config"
+	"github.com/ukuvago/angel-platform/internal/database"
+	"github.com/ukuvago/angel-platform/internal/models"
+	"github.com/ukuvago/angel-platform/internal/services"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// PostgresEnv names the variable holding a Postgres URL to run database
+// tests against. Tests needing Postgres are skipped when it is unset.
+const PostgresEnv = "TEST_POSTGRES_URL"
+
+// Config returns configuration for a test: uploads go to temporary
+// directories, emails are logged rather than sent, and payments use the fake
+// provider
+func Config(t testing.TB) *config.Config {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	cfg := config.Load()
+	cfg.DatabaseType = "sqlite"
+	cfg.DatabaseURL = filepath.Join(dir, "test.db") + "?_pragma=busy_timeout(10000)"
+	cfg.JWTSecret = "test-secret"
+	cfg.UploadDir = filepath.Join(dir, "uploads")
+	cfg.KYCUploadDir = filepath.Join(dir, "kyc")
+	cfg.SMTPHost = ""
+	cfg.DemoMode = true
+	cfg.StripeSecretKey = ""
+	cfg.StripeWebhookSecret = ""
+	cfg.PaystackSecretKey = ""
+	cfg.CurrencyProviders = map[string]string{}
+	cfg.OIDCProviders = map[string]config.OIDCProvider{}
+	cfg.LoginLimiter = "memory"
+	cfg.ExpiryJobInterval = 0
+	return cfg
+}
+
+// SQLite points the database at a fresh SQLite file for the test
+func SQLite(t testing.TB, cfg *config.Config) {
+	t.Helper()
+	open(t, cfg)
+}
+
+// Postgres points the database at a schema of its own in the Postgres
+// database named by PostgresEnv, dropped when the test ends
+func Postgres(t testing.TB, cfg *config.Config) {
+	t.Helper()
+	url := os.Getenv(PostgresEnv)
+	if url == "" {
+		t.Skip(PostgresEnv + " is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	cfg.DatabaseType = "postgres"
+	if strings.Contains(url, "://") {
+		separator := "?"
+		if strings.Contains(url, "?") {
+			separator = "&"
+		}
+		cfg.DatabaseURL = url + separator + "search_path=" + schema
+	} else {
+		cfg.DatabaseURL = url + " search_path=" + schema
+	}
+	open(t, cfg)
+}
+
+func open(t testing.TB, cfg *config.Config) {
+	t.Helper()
+	if err := database.Initialize(cfg); err != nil {
+		t.Fatalf("initialize database: %v", err)
+	}
+	db := database.DB
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// Database returns configuration for a test with a fresh SQLite database
+func Database(t testing.TB) *config.Config {
+	t.Helper()
+	cfg := Config(t)
+	SQLite(t, cfg)
+	return cfg
+}
+
+// User creates a user of the role with a verified email address
+func User(t testing.TB, role models.UserRole) *models.User {
+	t.Helper()
+	user := &models.User{
+		Email:         fmt.Sprintf("%s-%s@example.com", role, uuid.NewString()[:8]),
+		FirstName:     "Test",
+		LastName:      string(role),
+		Role:          role,
+		EmailVerified: true,
+	}
+	if err := database.GetDB().Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// Token signs the user in and returns an access token for them
+func Token(t testing.TB, cfg *config.Config, user *models.User) string {
+	t.Helper()
+	tokens, err := services.NewAuthService(cfg).StartSession(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	return tokens.AccessToken
+}
+
+// Project creates a project owned by the developer in the given status
+func Project(t testing.TB, developer *models.User, status models.ProjectStatus) *models.Project {
+	t.Helper()
+	var category models.Category
+	if err := database.GetDB().First(&category).Error; err != nil {
+		t.Fatalf("load category: %v", err)
+	}
+
+	project := &models.Project{
+		DeveloperID:   developer.ID,
+		CategoryID:    category.ID,
+		Title:         "Test project",
+		Tagline:       "A project for tests",
+		Description:   "Confidential details",
+		ContactEmail:  developer.Email,
+		MinInvestment: 1000,
+		MaxInvestment: 100000,
+		EquityOffered: 10,
+		Status:        status,
+	}
+	if err := database.GetDB().Create(project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	owner := &models.ProjectCollaborator{
+		ProjectID:  project.ID,
+		UserID:     developer.ID,
+		Permission: models.ProjectPermissionOwner,
+	}
+	if err := database.GetDB().Create(owner).Error; err != nil {
+		t.Fatalf("create project owner: %v", err)
+	}
+	return project
+}
+
+// Do sends a JSON request to the router, authenticated with token unless it
+// is empty, and decodes the JSON response
+func Do(t testing.TB, router http.Handler, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
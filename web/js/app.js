@@ -3,17 +3,59 @@
 const API_BASE = '/api';
 let currentUser = null;
 let authToken = localStorage.getItem('token');
+let refreshToken = localStorage.getItem('refresh_token');
+
+// authFetch sends a request with the access token. Access tokens are
+// short-lived, so on a 401 it renews them once with the refresh token.
+async function authFetch(endpoint, config = {}) {
+    const send = () => {
+        const headers = { ...(config.headers || {}) };
+        if (authToken) headers['Authorization'] = `Bearer ${authToken}`;
+        return fetch(API_BASE + endpoint, { ...config, headers });
+    };
+
+    let res = await send();
+    if (res.status === 401 && authToken && await refreshSession()) {
+        res = await send();
+    }
+    return res;
+}
+
+// Renews the session's tokens; concurrent callers share one refresh
+let refreshing = null;
+function refreshSession() {
+    if (!refreshToken) return Promise.resolve(false);
+    if (!refreshing) {
+        refreshing = fetch(API_BASE + '/auth/refresh', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        })
+            .then(async res => {
+                if (!res.ok) return false;
+                storeTokens(await res.json());
+                return true;
+            })
+            .catch(() => false)
+            .finally(() => { refreshing = null; });
+    }
+    return refreshing;
+}
+
+function storeTokens(data) {
+    authToken = data.token;
+    refreshToken = data.refresh_token;
+    localStorage.setItem('token', authToken);
+    localStorage.setItem('refresh_token', refreshToken);
+}
 
 // API Client
 const api = {
     async request(method, endpoint, data = null) {
-        const headers = { 'Content-Type': 'application/json' };
-        if (authToken) headers['Authorization'] = `Bearer ${authToken}`;
-
-        const config = { method, headers };
+        const config = { method, headers: { 'Content-Type': 'application/json' } };
         if (data) config.body = JSON.stringify(data);
 
-        const res = await fetch(API_BASE + endpoint, config);
+        const res = await authFetch(endpoint, config);
         const json = await res.json();
 
         if (!res.ok) throw new Error(json.error || 'Request failed');
@@ -28,29 +70,118 @@ const api = {
 
 // Auth functions
 async function login(email, password) {
-    const data = await api.post('/auth/login', { email, password });
-    authToken = data.token;
+    const data = await passSecondFactor(await api.post('/auth/login', { email, password }));
+    storeTokens(data);
     currentUser = data.user;
-    localStorage.setItem('token', authToken);
     return data;
 }
 
+// passSecondFactor asks 2FA users for their code when a sign-in step
+// returned a challenge instead of tokens
+async function passSecondFactor(data) {
+    if (!data.challenge_token) return data;
+    const code = prompt('Enter the code from your authenticator app, or a recovery code');
+    if (!code) throw new Error('Authentication code required');
+    return api.post('/auth/2fa/verify', { challenge_token: data.challenge_token, code });
+}
+
 async function register(userData) {
     const data = await api.post('/auth/register', userData);
-    authToken = data.token;
+    storeTokens(data);
     currentUser = data.user;
-    localStorage.setItem('token', authToken);
     return data;
 }
 
+// loadOIDCProviders adds a button for each configured identity provider to
+// the login and register pages. Registering passes the chosen role along.
+async function loadOIDCProviders() {
+    let providers = [];
+    try {
+        providers = (await api.get('/auth/oidc/providers')).providers || [];
+    } catch { }
+    document.querySelectorAll('.oidc-providers').forEach(container => {
+        container.innerHTML = '';
+        providers.forEach(name => {
+            const btn = document.createElement('button');
+            btn.type = 'button';
+            btn.className = 'btn btn-outline w-full mt-md';
+            btn.textContent = 'Continue with ' + name.charAt(0).toUpperCase() + name.slice(1);
+            btn.addEventListener('click', () => {
+                let url = API_BASE + '/auth/oidc/' + encodeURIComponent(name) + '/login';
+                if (container.dataset.oidcMode === 'register') {
+                    url += '?role=' + encodeURIComponent(document.querySelector('#register-form [name=role]').value);
+                }
+                window.location.href = url;
+            });
+            container.appendChild(btn);
+        });
+    });
+}
+
+// completeOIDCLogin signs in with the result a provider sign-in left in the
+// URL fragment of the callback page
+async function completeOIDCLogin() {
+    if (window.location.pathname !== '/oidc-callback') return;
+    let data = Object.fromEntries(new URLSearchParams(window.location.hash.slice(1)));
+    window.history.replaceState(null, '', '/');
+    try {
+        if (data.error) throw new Error(data.error);
+        data = await passSecondFactor(data);
+        storeTokens(data);
+        await checkAuth();
+        updateNav();
+        showPage('dashboard');
+    } catch (err) {
+        showToast(err.message, 'error');
+        showPage('login');
+    }
+}
+
+// completeMagicLink signs in with an emailed link once the user confirms, so
+// link scanners that open the page do not use the link up
+async function completeMagicLink() {
+    if (window.location.pathname !== '/magic-link') return;
+    const token = new URLSearchParams(window.location.search).get('token');
+    window.history.replaceState(null, '', '/');
+    if (!token || !confirm('Sign in to UkuvaGo with this link?')) return;
+    try {
+        const data = await passSecondFactor(await api.post('/auth/magic-link/consume', { token }));
+        storeTokens(data);
+        await checkAuth();
+        updateNav();
+        showPage('dashboard');
+    } catch (err) {
+        showToast(err.message, 'error');
+        showPage('login');
+    }
+}
+
+window.requestMagicLink = async function () {
+    const email = document.querySelector('#login-form [name=email]').value;
+    if (!email) {
+        showToast('Enter your email address first', 'error');
+        return;
+    }
+    try {
+        const res = await api.post('/auth/magic-link', { email });
+        showToast(res.message, 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+};
+
 function clearAuth() {
     authToken = null;
+    refreshToken = null;
     currentUser = null;
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     updateNav();
 }
 
-function logout() {
+async function logout() {
+    // End the session on the server too, so the tokens stop working
+    try { await api.post('/auth/logout'); } catch { }
     clearAuth();
     showPage('home');
 }
@@ -62,11 +193,29 @@ async function checkAuth() {
         currentUser = data.user;
         return currentUser;
     } catch {
+        // An expired impersonation token hands back to the admin's own session
+        if (impersonating()) {
+            stopImpersonating();
+            return null;
+        }
         clearAuth();
         return null; // Don't redirect here, just clear state
     }
 }
 
+// While an admin views the platform as a user, their own tokens are kept here
+function impersonating() {
+    return !!localStorage.getItem('impersonator_tokens');
+}
+
+window.stopImpersonating = function () {
+    const saved = JSON.parse(localStorage.getItem('impersonator_tokens'));
+    localStorage.removeItem('impersonator_tokens');
+    storeTokens(saved);
+    window.location.hash = 'admin';
+    window.location.reload();
+}
+
 // Toast notifications
 function showToast(message, type = 'info') {
     const container = document.getElementById('toast-container') || createToastContainer();
@@ -96,15 +245,18 @@ function updateNav() {
         authNav.classList.add('hidden');
         userNav.classList.remove('hidden');
 
+        const viewingAs = impersonating();
         userNav.innerHTML = `
         <div class="dropdown">
             <button class="btn btn-secondary" onclick="toggleDropdown()">
-                ${currentUser.first_name} (${currentUser.role}) ▾
+                ${viewingAs ? 'Viewing as ' : ''}${currentUser.first_name} (${currentUser.role}) ▾
             </button>
             <div id="user-dropdown" class="dropdown-content hidden">
                 <a href="#dashboard">Dashboard</a>
                 <a href="#profile">My Profile</a>
-                <a href="#" onclick="logout()">Logout</a>
+                ${viewingAs
+                ? '<a href="#" onclick="stopImpersonating()">Stop Viewing</a>'
+                : '<a href="#" onclick="logout()">Logout</a>'}
             </div>
         </div>`;
     } else {
@@ -255,6 +407,12 @@ document.addEventListener('DOMContentLoaded', async () => {
     // Check auth in background
     await checkAuth();
     updateNav();
+    await verifyEmailFromLink();
+    await completeOIDCLogin();
+    await completeMagicLink();
+    storeInvitationFromLink();
+    await acceptPendingInvitation();
+    loadOIDCProviders();
 
     const currentHash = window.location.hash.slice(1) || 'home';
     if (currentUser && ['login', 'register'].includes(currentHash)) {
@@ -291,6 +449,245 @@ function loadProfile() {
     form.last_name.value = currentUser.last_name;
     form.company_name.value = currentUser.company_name || '';
     form.email.value = currentUser.email;
+    document.getElementById('email-unverified')?.classList.toggle('hidden', currentUser.email_verified);
+    loadKYCStatus();
+    loadOrganization();
+}
+
+// Investor verification
+async function loadKYCStatus() {
+    const section = document.getElementById('kyc-section');
+    if (!section) return;
+    section.classList.toggle('hidden', currentUser?.role !== 'investor');
+    if (currentUser?.role !== 'investor') return;
+
+    try {
+        const data = await api.get('/kyc');
+        const badge = document.getElementById('kyc-status');
+        badge.textContent = data.status;
+        badge.className = 'badge ' + (data.status === 'approved' ? 'badge-success' : 'badge-warning');
+
+        const rejection = document.getElementById('kyc-rejection');
+        const reason = data.status === 'rejected' ? data.submission?.rejection_reason : '';
+        rejection.textContent = reason ? 'Reason: ' + reason : '';
+        rejection.classList.toggle('hidden', !reason);
+
+        document.getElementById('kyc-form').classList.toggle('hidden', data.status === 'approved' || data.status === 'pending');
+    } catch (err) {
+        // Unverified emails cannot use KYC yet; the profile shows why
+        section.classList.add('hidden');
+    }
+}
+
+document.getElementById('kyc-form')?.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const btn = e.target.querySelector('button[type="submit"]');
+    btn.disabled = true;
+    try {
+        const res = await authFetch('/kyc', { method: 'POST', body: new FormData(e.target) });
+        const json = await res.json();
+        if (!res.ok) throw new Error(json.error || 'Upload failed');
+        e.target.reset();
+        showToast(json.message, 'success');
+        loadKYCStatus();
+    } catch (err) {
+        showToast(err.message, 'error');
+    } finally {
+        btn.disabled = false;
+    }
+});
+
+// Organisations
+let myOrganization = null;
+
+async function loadOrganization() {
+    const section = document.getElementById('organization-section');
+    if (!section) return;
+    const eligible = ['investor', 'developer'].includes(currentUser?.role);
+    section.classList.toggle('hidden', !eligible);
+    if (!eligible) return;
+
+    const create = document.getElementById('organization-create-form');
+    const details = document.getElementById('organization-details');
+    try {
+        const data = await api.get('/organizations/mine');
+        myOrganization = data.organization;
+        create.classList.toggle('hidden', !!myOrganization);
+        details.classList.toggle('hidden', !myOrganization);
+        if (!myOrganization) return;
+
+        const isOwner = data.membership.role === 'owner';
+        document.getElementById('organization-name').textContent = myOrganization.name;
+        document.getElementById('organization-role').textContent = data.membership.role;
+        document.getElementById('organization-invite-form').classList.toggle('hidden', !isOwner);
+
+        const members = document.getElementById('organization-members');
+        members.innerHTML = '';
+        (myOrganization.members || []).forEach(m => {
+            const row = document.createElement('tr');
+            const name = document.createElement('td');
+            name.textContent = `${m.user?.first_name || ''} ${m.user?.last_name || ''} (${m.user?.email || ''})`;
+            const role = document.createElement('td');
+            const actions = document.createElement('td');
+            actions.className = 'text-right';
+            const isSelf = m.user_id === currentUser.id;
+
+            if (isOwner) {
+                const select = document.createElement('select');
+                select.className = 'form-control';
+                ['owner', 'member', 'viewer'].forEach(r => select.add(new Option(r, r, false, r === m.role)));
+                select.addEventListener('change', () => updateOrganizationMember(m.user_id, select.value));
+                role.appendChild(select);
+            } else {
+                role.textContent = m.role;
+            }
+            if (isOwner || isSelf) {
+                const btn = document.createElement('button');
+                btn.className = 'btn btn-outline btn-sm text-error';
+                btn.textContent = isSelf ? 'Leave' : 'Remove';
+                btn.addEventListener('click', () => removeOrganizationMember(m.user_id, isSelf));
+                actions.appendChild(btn);
+            }
+            row.append(name, role, actions);
+            members.appendChild(row);
+        });
+
+        const invitations = document.getElementById('organization-invitations');
+        invitations.innerHTML = '';
+        (myOrganization.invitations || []).forEach(inv => {
+            const li = document.createElement('li');
+            li.className = 'text-sm';
+            li.textContent = `${inv.email} (${inv.role}) `;
+            const btn = document.createElement('button');
+            btn.type = 'button';
+            btn.className = 'btn btn-outline btn-sm';
+            btn.textContent = 'Revoke';
+            btn.addEventListener('click', () => revokeOrganizationInvitation(inv.id));
+            li.appendChild(btn);
+            invitations.appendChild(li);
+        });
+    } catch (err) {
+        // Unverified emails cannot create organisations yet; the profile shows why
+        section.classList.add('hidden');
+    }
+}
+
+document.getElementById('organization-create-form')?.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    try {
+        const res = await api.post('/organizations', { name: e.target.name.value });
+        e.target.reset();
+        showToast(res.message, 'success');
+        loadOrganization();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+});
+
+document.getElementById('organization-invite-form')?.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    try {
+        const res = await api.post(`/organizations/${myOrganization.id}/invitations`, {
+            email: e.target.email.value,
+            role: e.target.role.value
+        });
+        e.target.reset();
+        showToast(res.message, 'success');
+        loadOrganization();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+});
+
+async function updateOrganizationMember(userId, role) {
+    try {
+        await api.put(`/organizations/${myOrganization.id}/members/${userId}`, { role });
+        showToast('Member updated', 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+    loadOrganization();
+}
+
+async function removeOrganizationMember(userId, isSelf) {
+    if (!confirm(isSelf ? 'Leave this organization?' : 'Remove this member?')) return;
+    try {
+        const res = await api.delete(`/organizations/${myOrganization.id}/members/${userId}`);
+        showToast(res.message, 'success');
+        loadOrganization();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+async function revokeOrganizationInvitation(id) {
+    try {
+        const res = await api.delete(`/organizations/${myOrganization.id}/invitations/${id}`);
+        showToast(res.message, 'success');
+        loadOrganization();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+// Where the token from each kind of invitation email is accepted
+const invitationLinks = {
+    '/organization-invite': '/organizations/invitations/accept',
+    '/project-invite': '/projects/invitations/accept'
+};
+
+// storeInvitationFromLink keeps the token from an organisation or project
+// invitation email until the user has signed in or registered to accept it
+function storeInvitationFromLink() {
+    const endpoint = invitationLinks[window.location.pathname];
+    if (!endpoint) return;
+    const token = new URLSearchParams(window.location.search).get('token');
+    window.history.replaceState(null, '', '/' + window.location.hash);
+    if (!token) return;
+    sessionStorage.setItem('pending_invite', JSON.stringify({ endpoint, token }));
+    if (!currentUser) {
+        showToast('Sign in or create an account to accept the invitation', 'info');
+        showPage('login');
+    }
+}
+
+async function acceptPendingInvitation() {
+    const pending = sessionStorage.getItem('pending_invite');
+    if (!pending || !currentUser) return;
+    sessionStorage.removeItem('pending_invite');
+    try {
+        const { endpoint, token } = JSON.parse(pending);
+        const res = await api.post(endpoint, { token });
+        currentUser.email_verified = true;
+        showToast(res.message, 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+window.resendVerification = async function () {
+    try {
+        const res = await api.post('/auth/resend-verification');
+        showToast(res.message, 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+};
+
+// verifyEmailFromLink completes verification when the page was opened from
+// the link in a verification email
+async function verifyEmailFromLink() {
+    if (window.location.pathname !== '/verify-email') return;
+    const token = new URLSearchParams(window.location.search).get('token');
+    window.history.replaceState(null, '', '/' + window.location.hash);
+    if (!token) return;
+    try {
+        const res = await api.post('/auth/verify-email?token=' + encodeURIComponent(token));
+        if (currentUser) currentUser.email_verified = true;
+        showToast(res.message, 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
 }
 
 document.getElementById('update-profile-form')?.addEventListener('submit', async (e) => {
@@ -318,6 +715,7 @@ document.getElementById('login-form')?.addEventListener('submit', async (e) => {
         showToast('Welcome back!', 'success');
         showPage(currentUser.role === 'admin' ? 'admin' : currentUser.role + '-dashboard');
         updateNav();
+        acceptPendingInvitation();
     } catch (err) {
         showToast(err.message, 'error');
     }
@@ -331,6 +729,7 @@ document.getElementById('register-form')?.addEventListener('submit', async (e) =
         showToast('Registration successful!', 'success');
         showPage(currentUser.role + '-dashboard');
         updateNav();
+        acceptPendingInvitation();
     } catch (err) {
         showToast(err.message, 'error');
     }
@@ -429,7 +828,8 @@ async function loadDeveloperDashboard() {
                 <td>${formatCurrency(p.min_investment)}</td>
                 <td style="text-align:right">
                     <button class="btn btn-sm btn-secondary" onclick="viewProject('${p.id}')">View</button>
-                    ${p.status === 'draft' ? `<button class="btn btn-sm btn-primary" onclick="submitProjectForReview('${p.id}')">Submit</button>` : ''}
+                    <button class="btn btn-sm btn-secondary" onclick="openCollaboratorsModal('${p.id}', ${p.permission === 'owner'})">Team</button>
+                    ${p.status === 'draft' && p.permission !== 'viewer' ? `<button class="btn btn-sm btn-primary" onclick="submitProjectForReview('${p.id}')">Submit</button>` : ''}
                 </td>
             </tr>
         `).join('');
@@ -463,6 +863,145 @@ async function submitProjectForReview(id) {
 // Expose functions
 window.submitProjectForReview = submitProjectForReview;
 
+// Project collaborators
+let collaboratorsProject = null;
+
+window.openCollaboratorsModal = async function (projectId, isOwner) {
+    collaboratorsProject = { id: projectId, isOwner };
+    document.getElementById('collaborators-modal').classList.remove('hidden');
+    document.getElementById('collaborator-invite-form').classList.toggle('hidden', !isOwner);
+    await loadCollaborators();
+};
+
+window.closeCollaboratorsModal = function () {
+    document.getElementById('collaborators-modal').classList.add('hidden');
+    collaboratorsProject = null;
+};
+
+async function loadCollaborators() {
+    if (!collaboratorsProject) return;
+    const { id, isOwner } = collaboratorsProject;
+    const list = document.getElementById('collaborators-list');
+    try {
+        const data = await api.get(`/projects/${id}/collaborators`);
+        list.innerHTML = '';
+        (data.collaborators || []).forEach(col => {
+            const row = document.createElement('tr');
+            const name = document.createElement('td');
+            name.textContent = `${col.user?.first_name || ''} ${col.user?.last_name || ''} (${col.user?.email || ''})`;
+            const permission = document.createElement('td');
+            const actions = document.createElement('td');
+            actions.className = 'text-right';
+            const isSelf = col.user_id === currentUser.id;
+
+            if (isOwner && col.permission !== 'owner') {
+                const select = document.createElement('select');
+                select.className = 'form-control';
+                ['editor', 'viewer'].forEach(p => select.add(new Option(p, p, false, p === col.permission)));
+                select.addEventListener('change', () => updateCollaborator(col.user_id, select.value));
+                permission.appendChild(select);
+
+                const transfer = document.createElement('button');
+                transfer.className = 'btn btn-outline btn-sm';
+                transfer.textContent = 'Make Owner';
+                transfer.addEventListener('click', () => transferProject(col.user_id));
+                actions.appendChild(transfer);
+            } else {
+                permission.textContent = col.permission;
+            }
+            if (col.permission !== 'owner' && (isOwner || isSelf)) {
+                const remove = document.createElement('button');
+                remove.className = 'btn btn-outline btn-sm text-error';
+                remove.textContent = isSelf ? 'Leave' : 'Remove';
+                remove.addEventListener('click', () => removeCollaborator(col.user_id, isSelf));
+                actions.appendChild(remove);
+            }
+            row.append(name, permission, actions);
+            list.appendChild(row);
+        });
+
+        const invitations = document.getElementById('collaborator-invitations');
+        invitations.innerHTML = '';
+        (data.invitations || []).forEach(inv => {
+            const li = document.createElement('li');
+            li.className = 'text-sm';
+            li.textContent = `${inv.email} (${inv.permission}) `;
+            const btn = document.createElement('button');
+            btn.type = 'button';
+            btn.className = 'btn btn-outline btn-sm';
+            btn.textContent = 'Revoke';
+            btn.addEventListener('click', () => revokeCollaboratorInvitation(inv.id));
+            li.appendChild(btn);
+            invitations.appendChild(li);
+        });
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+document.getElementById('collaborator-invite-form')?.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    try {
+        const res = await api.post(`/projects/${collaboratorsProject.id}/collaborators/invitations`, {
+            email: e.target.email.value,
+            permission: e.target.permission.value
+        });
+        e.target.reset();
+        showToast(res.message, 'success');
+        loadCollaborators();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+});
+
+async function updateCollaborator(userId, permission) {
+    try {
+        await api.put(`/projects/${collaboratorsProject.id}/collaborators/${userId}`, { permission });
+        showToast('Collaborator updated', 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+    loadCollaborators();
+}
+
+async function removeCollaborator(userId, isSelf) {
+    if (!confirm(isSelf ? 'Leave this project?' : 'Remove this collaborator?')) return;
+    try {
+        const res = await api.delete(`/projects/${collaboratorsProject.id}/collaborators/${userId}`);
+        showToast(res.message, 'success');
+        if (isSelf) {
+            closeCollaboratorsModal();
+            loadDeveloperDashboard();
+        } else {
+            loadCollaborators();
+        }
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+async function transferProject(userId) {
+    if (!confirm('Make this collaborator the project owner? You will stay on as an editor.')) return;
+    try {
+        const res = await api.post(`/projects/${collaboratorsProject.id}/transfer`, { user_id: userId });
+        showToast(res.message, 'success');
+        closeCollaboratorsModal();
+        loadDeveloperDashboard();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+async function revokeCollaboratorInvitation(id) {
+    try {
+        const res = await api.delete(`/projects/${collaboratorsProject.id}/collaborators/invitations/${id}`);
+        showToast(res.message, 'success');
+        loadCollaborators();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
 // Create Project Logic
 // Initial Load for Create Project
 async function loadCreateProject() {
@@ -544,12 +1083,8 @@ document.getElementById('create-project-form')?.addEventListener('submit', async
     btn.textContent = 'Submitting...';
 
     try {
-        const token = localStorage.getItem('token');
-        const res = await fetch(API_BASE + '/projects', {
+        const res = await authFetch('/projects', {
             method: 'POST',
-            headers: {
-                'Authorization': `Bearer ${token}`
-            },
             body: formData
         });
 
@@ -587,10 +1122,14 @@ window.switchAdminTab = function (tab) {
     const pendingContainer = document.getElementById('admin-pending-container');
     const allContainer = document.getElementById('admin-all-container');
     const categoriesContainer = document.getElementById('admin-categories-container');
+    const kycContainer = document.getElementById('admin-kyc-container');
+    const usersContainer = document.getElementById('admin-users-container');
 
     pendingContainer.classList.add('hidden');
     allContainer.classList.add('hidden');
     categoriesContainer.classList.add('hidden');
+    kycContainer.classList.add('hidden');
+    usersContainer.classList.add('hidden');
 
     if (tab === 'pending') {
         pendingContainer.classList.remove('hidden');
@@ -601,6 +1140,12 @@ window.switchAdminTab = function (tab) {
     } else if (tab === 'categories') {
         categoriesContainer.classList.remove('hidden');
         loadAdminCategories();
+    } else if (tab === 'kyc') {
+        kycContainer.classList.remove('hidden');
+        loadAdminKYCQueue();
+    } else if (tab === 'users') {
+        usersContainer.classList.remove('hidden');
+        loadAdminUsers();
     }
 }
 
@@ -806,6 +1351,166 @@ async function rejectProject(id) {
     }
 }
 
+async function loadAdminKYCQueue() {
+    const tbody = document.getElementById('admin-kyc-list');
+    if (!tbody) return;
+
+    tbody.innerHTML = '<tr><td colspan="4">Loading...</td></tr>';
+    try {
+        const data = await api.get('/admin/kyc');
+        if (!data.submissions || data.submissions.length === 0) {
+            tbody.innerHTML = '<tr><td colspan="4">No submissions awaiting review</td></tr>';
+            return;
+        }
+        tbody.innerHTML = data.submissions.map(s => `
+            <tr>
+                <td>${s.investor?.first_name} ${s.investor?.last_name}<br><span class="text-xs text-secondary">${s.investor?.email}</span></td>
+                <td>${(s.documents || []).map(d => `<a href="#" onclick="downloadKYCDocument('${d.id}', '${d.type}'); return false;">${d.type}</a>`).join(', ')}</td>
+                <td>${new Date(s.created_at).toLocaleDateString()}</td>
+                <td class="text-right">
+                    <button class="btn btn-primary btn-sm" onclick="reviewKYC('${s.id}', true)">Approve</button>
+                    <button class="btn btn-outline btn-sm text-error" onclick="reviewKYC('${s.id}', false)">Reject</button>
+                </td>
+            </tr>
+        `).join('');
+    } catch (err) {
+        tbody.innerHTML = '<tr><td colspan="4" class="text-error">Failed to load</td></tr>';
+    }
+}
+
+// KYC documents need the access token, so they are fetched rather than linked
+window.downloadKYCDocument = async function (id, type) {
+    try {
+        const res = await authFetch(`/admin/kyc/documents/${id}`);
+        if (!res.ok) throw new Error('Failed to download document');
+        const match = /filename="([^"]+)"/.exec(res.headers.get('Content-Disposition') || '');
+        const url = URL.createObjectURL(await res.blob());
+        const a = document.createElement('a');
+        a.href = url;
+        a.download = match ? match[1] : `kyc-${type}`;
+        a.click();
+        URL.revokeObjectURL(url);
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+window.reviewKYC = async function (id, approved) {
+    let reason = '';
+    if (approved) {
+        if (!confirm('Approve this investor?')) return;
+    } else {
+        reason = prompt('Please enter a reason for rejection:');
+        if (reason === null) return; // Cancelled
+        if (!reason.trim()) { alert('Reason is required'); return; }
+    }
+
+    try {
+        await api.post(`/admin/kyc/${id}/review`, { approved, reason });
+        showToast(approved ? 'Investor verified' : 'Submission rejected', approved ? 'success' : 'info');
+        loadAdminKYCQueue();
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+async function loadAdminUsers() {
+    const tbody = document.getElementById('admin-users-list');
+    tbody.innerHTML = '<tr><td colspan="4">Loading...</td></tr>';
+    try {
+        const data = await api.get('/admin/users');
+        const users = (data.users || []).filter(u => u.id !== currentUser?.id);
+        if (users.length === 0) {
+            tbody.innerHTML = '<tr><td colspan="4">No other users</td></tr>';
+            return;
+        }
+        tbody.innerHTML = users.map(u => `
+            <tr>
+                <td>${u.first_name} ${u.last_name}<br><span class="text-xs text-secondary">${u.email}</span></td>
+                <td>
+                    <select class="form-input" onchange="changeUserRole('${u.id}', this.value)">
+                        ${['investor', 'developer', 'admin'].map(r => `<option value="${r}" ${r === u.role ? 'selected' : ''}>${r}</option>`).join('')}
+                    </select>
+                </td>
+                <td>${u.suspended ? `<span class="text-error">Suspended</span><br><span class="text-xs text-secondary">${u.suspend_reason || ''}</span>` : 'Active'}</td>
+                <td class="text-right">
+                    ${u.suspended
+                ? `<button class="btn btn-outline btn-sm" onclick="unsuspendUser('${u.id}')">Unsuspend</button>`
+                : `<button class="btn btn-outline btn-sm" onclick="suspendUser('${u.id}')">Suspend</button>`}
+                    ${u.role !== 'admin' && !u.suspended ? `<button class="btn btn-outline btn-sm" onclick="impersonateUser('${u.id}')">View As</button>` : ''}
+                    <button class="btn btn-outline btn-sm" onclick="forcePasswordReset('${u.id}')">Reset Password</button>
+                    <button class="btn btn-outline btn-sm" onclick="revokeUserSessions('${u.id}')">Sign Out</button>
+                    <button class="btn btn-outline btn-sm text-error" onclick="deleteUser('${u.id}')">Delete</button>
+                </td>
+            </tr>
+        `).join('');
+    } catch (err) {
+        tbody.innerHTML = '<tr><td colspan="4" class="text-error">Failed to load</td></tr>';
+    }
+}
+
+// adminUserAction runs an action on a user and reloads the list
+async function adminUserAction(request) {
+    try {
+        const res = await request();
+        showToast(res.message, 'success');
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+    loadAdminUsers();
+}
+
+window.suspendUser = function (id) {
+    const reason = prompt('Reason for suspending this account:');
+    if (reason === null) return; // Cancelled
+    adminUserAction(() => api.post(`/admin/users/${id}/suspend`, { reason }));
+}
+
+window.unsuspendUser = function (id) {
+    adminUserAction(() => api.post(`/admin/users/${id}/unsuspend`));
+}
+
+window.changeUserRole = function (id, role) {
+    if (!confirm(`Change this user's role to ${role}? They will be signed out.`)) {
+        loadAdminUsers();
+        return;
+    }
+    adminUserAction(() => api.put(`/admin/users/${id}/role`, { role }));
+}
+
+window.forcePasswordReset = function (id) {
+    if (!confirm('Clear this user\'s password and email them a reset link?')) return;
+    adminUserAction(() => api.post(`/admin/users/${id}/reset-password`));
+}
+
+window.revokeUserSessions = function (id) {
+    if (!confirm('Sign this user out on every device?')) return;
+    adminUserAction(() => api.post(`/admin/users/${id}/revoke-sessions`));
+}
+
+// impersonateUser switches to a read-only token for seeing the platform as
+// the user, keeping the admin's tokens to switch back with
+window.impersonateUser = async function (id) {
+    const reason = prompt('Why do you need to view the platform as this user? They will be emailed.');
+    if (!reason || !reason.trim()) return;
+    try {
+        const data = await api.post(`/admin/users/${id}/impersonate`, { reason });
+        localStorage.setItem('impersonator_tokens', JSON.stringify({ token: authToken, refresh_token: refreshToken }));
+        storeTokens({ token: data.token, refresh_token: '' });
+        currentUser = data.user;
+        updateNav();
+        showToast(`Viewing as ${data.user.email}. Changes are blocked.`, 'info');
+        window.location.hash = 'dashboard';
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+}
+
+window.deleteUser = function (id) {
+    if (!confirm('Delete this account? Personal details are erased and cannot be recovered.')) return;
+    adminUserAction(() => api.delete(`/admin/users/${id}`));
+}
+
 // Expose admin functions
 window.loadAdminPendingProjects = loadAdminPendingProjects;
 window.approveProject = approveProject;